@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// this file walks a graph in a stable order and writes it out in formats
+// that other tools can draw.
+
+// names for node types in exported graphs.
+var typeNames = map[NodeType]string{
+	RootType: "root",
+	AndType:  "and",
+	OrType:   "or",
+}
+
+// DOT shapes for node types.
+var typeShapes = map[NodeType]string{
+	RootType: "diamond",
+	AndType:  "box",
+	OrType:   "ellipse",
+}
+
+// An Edge is a link from a parent node to one of its children.
+type Edge struct {
+	Parent, Child *Node
+}
+
+// Nodes returns the nodes in the graph, sorted by name.
+func (g Graph) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(g))
+	for _, node := range g {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// Edges returns the links between nodes in the graph, sorted by child name,
+// then by the order of the child's parents. Links to nodes outside the graph
+// are left out.
+func (g Graph) Edges() []Edge {
+	edges := make([]Edge, 0)
+	for _, child := range g.Nodes() {
+		for _, parent := range child.parents {
+			if g[parent.Name] == parent {
+				edges = append(edges, Edge{Parent: parent, Child: child})
+			}
+		}
+	}
+	return edges
+}
+
+// Attrs returns extra attributes for a node in an exported graph, which are
+// added to or replace the defaults. It may return nil.
+type Attrs func(*Node) map[string]string
+
+// nodeAttrs returns the attributes of a node, with sorted keys.
+func nodeAttrs(node *Node, defaults map[string]string,
+	attrs Attrs) (map[string]string, []string) {
+	m := make(map[string]string, len(defaults))
+	for k, v := range defaults {
+		m[k] = v
+	}
+	if attrs != nil {
+		for k, v := range attrs(node) {
+			m[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return m, keys
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with edges pointing from
+// parents to children. Nodes are shaped by type, slots are drawn with double
+// borders, and hard nodes are dashed.
+func (g Graph) WriteDOT(w io.Writer, attrs Attrs) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph {")
+	for _, node := range g.Nodes() {
+		defaults := map[string]string{"shape": typeShapes[node.Type]}
+		if node.IsSlot {
+			defaults["peripheries"] = "2"
+		}
+		if node.IsHard {
+			defaults["style"] = "dashed"
+		}
+		m, keys := nodeAttrs(node, defaults, attrs)
+
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s=%s", k, dotQuote(m[k]))
+		}
+		fmt.Fprintf(bw, "\t%s [%s];\n", dotQuote(node.Name),
+			strings.Join(pairs, ", "))
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(bw, "\t%s -> %s;\n", dotQuote(edge.Parent.Name),
+			dotQuote(edge.Child.Name))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a quoted DOT ID.
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// WriteGraphML writes the graph in GraphML format, with edges pointing from
+// parents to children. Each node has its type and whether it's a slot or hard
+// as data, plus any extra attributes as string data.
+func (g Graph) WriteGraphML(w io.Writer, attrs Attrs) error {
+	nodes := g.Nodes()
+
+	// every data key has to be declared up front.
+	extra := make(map[string]bool)
+	if attrs != nil {
+		for _, node := range nodes {
+			for k := range attrs(node) {
+				extra[k] = true
+			}
+		}
+	}
+	extraKeys := make([]string, 0, len(extra))
+	for k := range extra {
+		switch k {
+		case "type", "slot", "hard":
+		default:
+			extraKeys = append(extraKeys, k)
+		}
+	}
+	sort.Strings(extraKeys)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, xml.Header+
+		`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="type" for="node" attr.name="type" `+
+		`attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="slot" for="node" attr.name="slot" `+
+		`attr.type="boolean"/>`)
+	fmt.Fprintln(bw, `  <key id="hard" for="node" attr.name="hard" `+
+		`attr.type="boolean"/>`)
+	for _, k := range extraKeys {
+		fmt.Fprintf(bw, "  <key id=%s for=\"node\" attr.name=%s "+
+			"attr.type=\"string\"/>\n", xmlQuote(k), xmlQuote(k))
+	}
+	fmt.Fprintln(bw, `  <graph edgedefault="directed">`)
+
+	for _, node := range nodes {
+		defaults := map[string]string{
+			"type": typeNames[node.Type],
+			"slot": fmt.Sprint(node.IsSlot),
+			"hard": fmt.Sprint(node.IsHard),
+		}
+		m, keys := nodeAttrs(node, defaults, attrs)
+
+		fmt.Fprintf(bw, "    <node id=%s>\n", xmlQuote(node.Name))
+		for _, k := range keys {
+			fmt.Fprintf(bw, "      <data key=%s>%s</data>\n", xmlQuote(k),
+				xmlEscape(m[k]))
+		}
+		fmt.Fprintln(bw, "    </node>")
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(bw, "    <edge source=%s target=%s/>\n",
+			xmlQuote(edge.Parent.Name), xmlQuote(edge.Child.Name))
+	}
+
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// xmlEscape returns s with XML special characters escaped.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlQuote returns s as a quoted XML attribute value.
+func xmlQuote(s string) string {
+	return `"` + xmlEscape(s) + `"`
+}
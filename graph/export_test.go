@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// returns a small graph with one of each node type and a hard slot.
+func newExportGraph() Graph {
+	g := New()
+	g.AddNodes(newNormalNode("start", AndType),
+		newNormalNode(`say "hi"`, RootType),
+		newNormalNode("a & b", OrType),
+		NewNode("slot", AndType, false, true, true))
+	g.AddParents(map[string][]string{
+		"a & b": []string{"start", `say "hi"`},
+		"slot":  []string{"a & b"},
+	})
+	return g
+}
+
+// tests that edges come out in a stable order and skip nodes outside the
+// graph.
+func TestEdges(t *testing.T) {
+	g := newExportGraph()
+	g["slot"].AddParents(newNormalNode("outside", RootType))
+
+	var got []string
+	for _, edge := range g.Edges() {
+		got = append(got, edge.Parent.Name+" -> "+edge.Child.Name)
+	}
+	want := []string{`start -> a & b`, `say "hi" -> a & b`, `a & b -> slot`}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := newExportGraph()
+	var b bytes.Buffer
+	if err := g.WriteDOT(&b, func(n *Node) map[string]string {
+		if n.Name == "slot" {
+			return map[string]string{"color": "red", "shape": "box3d"}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph {
+	"a & b" [shape="ellipse"];
+	"say \"hi\"" [shape="diamond"];
+	"slot" [color="red", peripheries="2", shape="box3d", style="dashed"];
+	"start" [shape="box"];
+	"start" -> "a & b";
+	"say \"hi\"" -> "a & b";
+	"a & b" -> "slot";
+}
+`
+	if b.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	g := newExportGraph()
+	var b bytes.Buffer
+	if err := g.WriteGraphML(&b, func(n *Node) map[string]string {
+		return map[string]string{"item": n.Name + " <"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// make sure the output parses, and check the parts that matter.
+	var doc struct {
+		Keys []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"key"`
+		Nodes []struct {
+			ID   string `xml:"id,attr"`
+			Data []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"data"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Keys) != 4 || doc.Keys[3].ID != "item" {
+		t.Errorf("want 4 keys ending with item, got %v", doc.Keys)
+	}
+	if len(doc.Nodes) != 4 || doc.Nodes[1].ID != `say "hi"` {
+		t.Fatalf("want 4 nodes sorted by name, got %v", doc.Nodes)
+	}
+	data := make(map[string]string)
+	for _, d := range doc.Nodes[2].Data {
+		data[d.Key] = d.Value
+	}
+	if data["type"] != "and" || data["slot"] != "true" ||
+		data["hard"] != "true" || data["item"] != "slot <" {
+		t.Errorf("wrong data for slot: %v", data)
+	}
+	if len(doc.Edges) != 3 || doc.Edges[2].Source != "a & b" ||
+		doc.Edges[2].Target != "slot" {
+		t.Errorf("wrong edges: %v", doc.Edges)
+	}
+}
@@ -41,18 +41,41 @@ func (g Graph) AddParents(links map[string][]string) {
 	}
 }
 
-// ClearMarks resets all the nodes in a graph to an "unknown" state. This is
-// required any time relationships in the graph change.
+// ClearMarks resets all the nodes in a graph to an "unknown" state. Changing
+// relationships through node methods invalidates only the affected marks, so
+// this is only required when nodes' marks are set directly, or when switching
+// between hard and non-hard evaluation.
 func (g Graph) ClearMarks() {
 	for _, node := range g {
 		node.Mark = MarkNone
 	}
 }
 
+// A Snapshot is a record of the marks of the nodes in a graph. It can be
+// restored as long as the relationships in the graph are the same as when the
+// snapshot was taken, which is cheaper than clearing and recomputing marks.
+type Snapshot map[*Node]Mark
+
+// Snapshot returns a record of the current marks in the graph.
+func (g Graph) Snapshot() Snapshot {
+	s := make(Snapshot, len(g))
+	for _, node := range g {
+		s[node] = node.Mark
+	}
+	return s
+}
+
+// Restore sets the marks in the graph to the ones in the snapshot.
+func (g Graph) Restore(s Snapshot) {
+	for node, mark := range s {
+		node.Mark = mark
+	}
+}
+
 // Explore returns a new set of all nodes reachable from the set of nodes in
 // start, adding the nodes in add. This is a destructive operation; at the end,
 // the graph will have all nodes in the return set set to MarkTrue and the rest
-// set to MarkNone.
+// set to MarkFalse or MarkNone.
 func (g Graph) Explore(start map[*Node]bool, hard bool,
 	add ...*Node) map[*Node]bool {
 	// copy set, and mark nodes accordingly
@@ -112,6 +135,45 @@ func (g Graph) ExploreFromStart(hard bool) map[*Node]bool {
 	return g.Explore(nil, hard, g["start"])
 }
 
+// Reachable returns the set of nodes that GetMark would find true, working
+// forward from And nodes without parents. Unlike GetMark, this takes the same
+// time no matter how many loops in the graph are unsatisfied, and it doesn't
+// use or change marks.
+func (g Graph) Reachable(hard bool) map[*Node]bool {
+	reached := make(map[*Node]bool)
+	satisfied := make(map[*Node]int) // number of reached parents
+	queue := make([]*Node, 0, len(g))
+	for _, node := range g {
+		if node.Type == AndType && len(node.parents) == 0 {
+			reached[node] = true
+			queue = append(queue, node)
+		}
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if !hard && node.IsHard {
+			continue
+		}
+		for _, child := range node.children {
+			if reached[child] {
+				continue
+			}
+			if child.Type == AndType {
+				satisfied[child]++
+				if satisfied[child] < len(child.parents) {
+					continue
+				}
+			}
+			reached[child] = true
+			queue = append(queue, child)
+		}
+	}
+
+	return reached
+}
+
 // Reduce returns a version of the graph that is 1. only relevant to the given
 // target and 2. reduced to as few nodes as possible.
 func (g Graph) Reduce(target string) (Graph, error) {
@@ -1,11 +1,40 @@
 package graph
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func newNormalNode(name string, nodeType NodeType) *Node {
 	return NewNode(name, nodeType, false, false, false)
 }
 
+// tests that restoring a snapshot undoes marks made after adding a parent
+func TestSnapshot(t *testing.T) {
+	g := New()
+	a := newNormalNode("A", OrType)
+	b := newNormalNode("B", OrType)
+	root := newNormalNode("root", AndType) // always true
+	g.AddNodes(a, b, root)
+	g.AddParents(map[string][]string{"A": []string{"B"}})
+
+	if a.GetMark(a, false) != MarkFalse {
+		t.Fatal("A reachable before adding root")
+	}
+	snapshot := g.Snapshot()
+
+	b.AddParents(root)
+	if a.GetMark(a, false) != MarkTrue {
+		t.Fatal("A not reachable after adding root")
+	}
+
+	b.RemoveParent(root)
+	g.Restore(snapshot)
+	if a.Mark == MarkTrue || a.GetMark(a, false) != MarkFalse {
+		t.Error("A reachable after restoring snapshot")
+	}
+}
+
 // tests Graph.Reduce on a graph that is effectively a linked list
 func TestListReduce(t *testing.T) {
 	g := New()
@@ -196,3 +225,125 @@ func isEquivalentNodeInSlice(node *Node, slice []*Node) bool {
 	}
 	return false
 }
+
+// tests that changing relationships updates marks without clearing them
+func TestInvalidate(t *testing.T) {
+	g := New()
+	root := newNormalNode("root", AndType) // always true
+	a := newNormalNode("A", OrType)
+	b := newNormalNode("B", AndType)
+	c := newNormalNode("C", OrType)
+	g.AddNodes(root, a, b, c)
+	g.AddParents(map[string][]string{"B": []string{"A"}, "C": []string{"B"}})
+
+	if c.GetMark(c, false) != MarkFalse {
+		t.Fatal("C reachable before adding root")
+	}
+	a.AddParents(root)
+	if c.GetMark(c, false) != MarkTrue {
+		t.Fatal("C not reachable after adding root")
+	}
+	b.AddParents(newNormalNode("D", OrType))
+	if c.GetMark(c, false) != MarkFalse {
+		t.Fatal("C reachable after adding false parent to B")
+	}
+	b.PopParent()
+	if c.GetMark(c, false) != MarkTrue {
+		t.Fatal("C not reachable after removing false parent from B")
+	}
+	a.RemoveParent(root)
+	if c.GetMark(c, false) != MarkFalse {
+		t.Fatal("C reachable after removing root")
+	}
+
+	// loops aren't cached as false, so they can still be satisfied later.
+	a.AddParents(c)
+	if c.GetMark(c, false) != MarkFalse {
+		t.Fatal("C reachable through a loop")
+	}
+	a.AddParents(root)
+	if c.GetMark(c, false) != MarkTrue {
+		t.Fatal("C not reachable after adding root to loop")
+	}
+}
+
+// tests that Graph.Reachable agrees with GetMark, including around loops and
+// hard nodes.
+func TestReachable(t *testing.T) {
+	g := New()
+	root := newNormalNode("root", AndType) // always true
+	a := newNormalNode("A", OrType)
+	b := newNormalNode("B", AndType)
+	c := newNormalNode("C", OrType)
+	h := NewNode("H", OrType, false, false, true)
+	d := newNormalNode("D", AndType)
+	g.AddNodes(root, a, b, c, h, d)
+	g.AddParents(map[string][]string{
+		"A": []string{"C"}, "B": []string{"A"}, "C": []string{"B"},
+		"H": []string{"root"}, "D": []string{"root", "H"},
+	})
+
+	check := func(hard bool) {
+		reached := g.Reachable(hard)
+		g.ClearMarks()
+		for _, node := range g {
+			if reached[node] != (node.GetMark(node, hard) == MarkTrue) {
+				t.Errorf("hard %v: %s reachable: %v", hard, node.Name,
+					reached[node])
+			}
+		}
+	}
+
+	check(false)
+	check(true)
+	a.AddParents(root)
+	check(false)
+	a.RemoveParent(root)
+	check(false)
+}
+
+// makes a chain of n And nodes, each the parent of the next, with the first
+// having a true parent.
+func makeChain(n int) []*Node {
+	nodes := make([]*Node, n)
+	for i := range nodes {
+		nodes[i] = newNormalNode(fmt.Sprintf("%d", i), AndType)
+		if i > 0 {
+			nodes[i].AddParents(nodes[i-1])
+		}
+	}
+	return nodes
+}
+
+// benchmarks re-evaluating the end of a chain after a change at the end, with
+// marks invalidated by the change.
+func BenchmarkUpdateIncremental(b *testing.B) {
+	nodes := makeChain(1000)
+	last, extra := nodes[len(nodes)-1], newNormalNode("extra", OrType)
+	last.GetMark(last, false)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		last.AddParents(extra)
+		last.GetMark(last, false)
+		last.RemoveParent(extra)
+		last.GetMark(last, false)
+	}
+}
+
+// benchmarks the same as BenchmarkUpdateIncremental, but clearing all marks
+// after each change, as was needed before marks were invalidated.
+func BenchmarkUpdateClearMarks(b *testing.B) {
+	nodes := makeChain(1000)
+	last, extra := nodes[len(nodes)-1], newNormalNode("extra", OrType)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		last.AddParents(extra)
+		clearMarks(nodes...)
+		last.GetMark(last, false)
+		last.RemoveParent(extra)
+		clearMarks(nodes...)
+		last.GetMark(last, false)
+	}
+}
@@ -8,8 +8,9 @@ import (
 // not itself define the graph.
 
 // Mark is the current state of a Node in its evaluation. When a non-root Node
-// is evaluated, it is set to MarkFalse until proven otherwise. This is to
-// prevent evaluating (infinite) loops in the graph.
+// is evaluated, it is set to MarkPending until proven otherwise. This is to
+// prevent evaluating (infinite) loops in the graph. Results are cached until
+// the relationships of the node or its ancestors change.
 type Mark int
 
 // NodeType determines how a node approaches GetMark(). And nodes return
@@ -27,8 +28,8 @@ const (
 	MarkFalse               // continue an OrNode, fail an AndNode
 	MarkPending             // prevents circular dependencies
 
-	// nodes will not ever set themselves to MarkFalse, but they will return it
-	// if they are set to MarkNone and are not satisfied
+	// nodes only set themselves to MarkFalse if the result didn't depend on a
+	// loop; otherwise they return it but stay MarkNone
 
 	RootType NodeType = iota
 	AndType
@@ -79,75 +80,95 @@ func NewNode(name string, nodeType NodeType,
 }
 
 func getAndMark(n *Node, hard bool) Mark {
-	if n.Mark == MarkNone {
-		n.Mark = MarkPending
-		for _, parent := range n.parents {
-			if !hard && parent.IsHard {
-				n.Mark = MarkFalse
-				return n.Mark
-			}
+	return settle(evalAnd(n, hard))
+}
 
-			switch parent.GetMark(parent, hard) {
-			case MarkPending, MarkFalse:
-				n.Mark = MarkNone
-				return MarkFalse
-			}
+func getOrMark(n *Node, hard bool) Mark {
+	return settle(evalOr(n, hard))
+}
+
+// converts an internal evaluation result to one for GetMark callers. a
+// pending result means the node was only false because of a loop, which is
+// still false from the outside.
+func settle(mark Mark) Mark {
+	if mark == MarkPending {
+		return MarkFalse
+	}
+	return mark
+}
+
+// evaluate returns the node's mark, computing and caching it if needed. true
+// and false results are cached; MarkPending is returned instead of false if
+// the result depended on a node that was still being evaluated, since that
+// result could change once the loop is resolved.
+func (n *Node) evaluate(hard bool) Mark {
+	if n.Type == AndType {
+		return evalAnd(n, hard)
+	}
+	return evalOr(n, hard)
+}
+
+func evalAnd(n *Node, hard bool) Mark {
+	if n.Mark != MarkNone {
+		return n.Mark
+	}
+
+	n.Mark = MarkPending
+	for _, parent := range n.parents {
+		if !hard && parent.IsHard {
+			n.Mark = MarkFalse
+			return n.Mark
 		}
-		if n.Mark == MarkPending {
-			n.Mark = MarkTrue
+
+		switch parent.evaluate(hard) {
+		case MarkFalse:
+			n.Mark = MarkFalse
+			return n.Mark
+		case MarkPending:
+			n.Mark = MarkNone
+			return MarkPending
 		}
 	}
 
+	n.Mark = MarkTrue
 	return n.Mark
 }
 
-func getOrMark(n *Node, hard bool) Mark {
-	if n.Mark == MarkNone {
-		n.Mark = MarkPending
-		allPending := true
-
-		// prioritize already satisfied nodes
-	OrPeekLoop:
-		for _, parent := range n.parents {
-			if !hard && parent.IsHard {
-				continue
-			}
+func evalOr(n *Node, hard bool) Mark {
+	if n.Mark != MarkNone {
+		return n.Mark
+	}
 
-			switch parent.Mark {
-			case MarkTrue:
-				n.Mark = MarkTrue
-				allPending = false
-				break OrPeekLoop
-			case MarkFalse:
-				allPending = false
-			}
+	// prioritize already satisfied nodes
+	n.Mark = MarkPending
+	for _, parent := range n.parents {
+		if (hard || !parent.IsHard) && parent.Mark == MarkTrue {
+			n.Mark = MarkTrue
+			return n.Mark
 		}
+	}
 
-		// then actually check them otherwise
-		if n.Mark == MarkPending {
-		OrGetLoop:
-			for _, parent := range n.parents {
-				if !hard && parent.IsHard {
-					continue
-				}
-
-				switch parent.GetMark(parent, hard) {
-				case MarkTrue:
-					n.Mark = MarkTrue
-					allPending = false
-					break OrGetLoop
-				case MarkFalse:
-					allPending = false
-				}
-			}
+	// then actually check them otherwise
+	looped := false
+	for _, parent := range n.parents {
+		if !hard && parent.IsHard {
+			continue
 		}
 
-		if (allPending && len(n.parents) > 0) || n.Mark == MarkPending {
-			n.Mark = MarkNone
-			return MarkFalse
+		switch parent.evaluate(hard) {
+		case MarkTrue:
+			n.Mark = MarkTrue
+			return n.Mark
+		case MarkPending:
+			looped = true
 		}
 	}
 
+	if looped {
+		n.Mark = MarkNone
+		return MarkPending
+	}
+	n.Mark = MarkFalse
 	return n.Mark
 }
 
@@ -166,23 +187,32 @@ func (n *Node) NumParents() int {
 
 // AddParents makes the given nodes parents of the node, and likewise adds this
 // node to each parent's list of children. If a given parent is already a
-// parent of the node, nothing is done.
+// parent of the node, nothing is done. A new parent can only make an And node
+// false or an Or node true, so the node's mark is invalidated only if it might
+// change.
 func (n *Node) AddParents(parents ...*Node) {
 	for _, parent := range parents {
 		if !IsNodeInSlice(parent, n.parents) {
 			n.parents = append(n.parents, parent)
 			addChild(n, parent)
+			if (n.Type == AndType) == (n.Mark == MarkTrue) {
+				n.invalidate()
+			}
 		}
 	}
 }
 
 // RemoveParent removes the given node from this node's parents. It panics if
-// the given node isn't actually a parent of this node.
+// the given node isn't actually a parent of this node. As with AddParents, the
+// node's mark is invalidated if it might change.
 func (n *Node) RemoveParent(parent *Node) {
 	for i, p := range n.parents {
 		if p == parent {
 			n.parents = append(n.parents[:i], n.parents[i+1:]...)
-			removeChild(parent, n)
+			removeChild(n, parent)
+			if (n.Type == AndType) == (n.Mark == MarkFalse) {
+				n.invalidate()
+			}
 			return
 		}
 	}
@@ -202,6 +232,21 @@ func (n *Node) PopParent() *Node {
 func (n *Node) ClearParents() {
 	removeChild(n, n.parents...)
 	n.parents = n.parents[:0]
+	n.invalidate()
+}
+
+// invalidate resets the mark of the node and of its descendants, so that marks
+// don't have to be cleared for the whole graph after its relationships change.
+// the walk stops at nodes without marks, since no cached mark was derived
+// through them.
+func (n *Node) invalidate() {
+	if n.Mark == MarkNone || n.Mark == MarkPending {
+		return
+	}
+	n.Mark = MarkNone
+	for _, child := range n.children {
+		child.invalidate()
+	}
 }
 
 // String satisfies the fmt.Stringer interface.
@@ -73,6 +73,19 @@ func TestNodeRelationships(t *testing.T) {
 		if len(n2.children) > 0 {
 			t.Errorf("node has children: %+v", n2)
 		}
+
+		// test removing a parent in a loop, which should only unlink the
+		// removed parent's child.
+		n1.AddParents(n2)
+		n2.AddParents(n1)
+		n1.RemoveParent(n2)
+		if len(n2.children) > 0 {
+			t.Errorf("node has children: %+v", n2)
+		}
+		if len(n1.children) == 0 {
+			t.Errorf("node has no children: %+v", n1)
+		}
+		n2.ClearParents()
 	}
 }
 
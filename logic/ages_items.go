@@ -31,9 +31,10 @@ var agesItemNodes = map[string]*Node{
 	"flippers":     Or("flippers 1", "flippers 2"),
 	"mermaid suit": And("flippers 1", "flippers 2"),
 
-	"bomb jump 2": And("feather", Or("pegasus satchel", Hard("bombs"))),
+	"bomb jump 2": And("feather", Or("pegasus satchel", Medium("bombs"))),
 	"jump 3":      And("feather", "pegasus satchel"),
-	"bomb jump 3": HardAnd("feather", "pegasus satchel", "bombs"),
+	"bomb jump 3": Trick("bomb-jump-3",
+		MediumAnd("feather", "pegasus satchel", "bombs")),
 
 	"seed item": Or("satchel", "seed shooter"),
 
@@ -49,7 +49,7 @@ var holodrumNodes = map[string]*Node{
 			"flippers", "jump 2", "ricky's flute", "dimitri's flute"))),
 	"fairy fountain": Or(
 		And("sunken city",
-			Or("eastern suburbs default spring", "spring", "gale satchel")),
+			Or("eastern suburbs default spring", "spring", "gale warp")),
 		And("suburbs", Or("eastern suburbs default winter", "winter",
 			"flippers", "jump 2", "ricky's flute", "dimitri's flute"))),
 	"moblin road": Or(
@@ -58,7 +58,7 @@ var holodrumNodes = map[string]*Node{
 			"sunken city default spring", "spring",
 			"sunken city default summer", "summer",
 			"sunken city default autumn", "autumn"),
-			Or("gale satchel", And(
+			Or("gale warp", And(
 				Or("eastern suburbs default winter", "winter"),
 				Or("eastern suburbs default spring", "spring"))))),
 	"holly's house": AndSlot("moblin road",
@@ -200,12 +200,12 @@ var holodrumNodes = map[string]*Node{
 	// sunken city
 	"sunken city": Or(
 		And("mount cucco", "flippers",
-			Or("summer", "sunken city default summer", "gale satchel")),
+			Or("summer", "sunken city default summer", "gale warp")),
 		And("fairy fountain", Or("eastern suburbs default spring", "spring")),
 		And("blaino's gym", Or(
 			And("natzu prairie", "flute"),
 			And("natzu river", And(Or("flippers", "flute"), "jump 2"),
-				And("flute", "flippers", "gale satchel")),
+				And("flute", "flippers", "gale warp")),
 			And("natzu wasteland", Or("flute", And("remove bush",
 				Or("bomb jump 3", And("jump 3", "flippers")))))))),
 	"sunken city seed tree": AndSlot("sunken city", "seed item",
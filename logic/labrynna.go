@@ -159,10 +159,12 @@ var labrynnaNodes = map[string]*Node{
 		And("lynna city", Or("feather", "ages"), "mermaid suit"),
 		And("ridge mid past", "feather", "brother emblem"),
 		And("ridge mid present", "ages"),
-		And("ridge base past west", Or("flippers", Hard("jump 3")))),
+		And("ridge base past west", Or("flippers",
+			Trick("pegasus-jump-across-water", Medium("jump 3"))))),
 	"ridge base past west": Or(
 		And("ridge base present", "echoes"),
-		And("ridge base past east", Or("flippers", Hard("jump 3")))),
+		And("ridge base past east", Or("flippers",
+			Trick("pegasus-jump-across-water", Medium("jump 3"))))),
 	"ridge base past":     AndSlot("ridge base past west", "bombs"),
 	"enter d6 past":       And("mermaid key", "ridge base past west"),
 	"ridge diamonds past": AndSlot("ridge base past west", "switch hook"),
@@ -192,7 +194,8 @@ var labrynnaNodes = map[string]*Node{
 	"zora NW cave":         AndSlot("zora village", "bombs", "power glove"),
 	"fairies' coast chest": AndSlot("zora village"),
 	// in hard logic, farm kills and get a potion off maple
-	"king zora":       AndSlot("zora village", Or("syrup", Hard())),
+	"king zora": AndSlot("zora village",
+		Or("syrup", Trick("maple-potion", Hard()))),
 	"library present": AndSlot("zora village", "library key"),
 	"library past": AndSlot("zora village", "library key",
 		Or("book of seals", "bomb jump 3")),
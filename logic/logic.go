@@ -2,6 +2,7 @@ package logic
 
 import (
 	"fmt"
+	"sort"
 )
 
 // This package contains definitions of nodes and node relationships before
@@ -36,18 +37,56 @@ const (
 	HardOrType
 )
 
+// A Tier is a level of logic difficulty. Nodes tagged with a tier above the one
+// in use are left out of the graph, so tricks can be toggled as a group.
+// Separately, the hard tier allows the use of "hard" nodes. The medium tier
+// adds bomb jumps and jumping across water without flippers.
+type Tier int
+
+const (
+	TierCasual Tier = iota
+	TierMedium
+	TierHard
+)
+
+var tierNames = []string{"casual", "medium", "hard"}
+
+// String returns the name of the tier.
+func (t Tier) String() string {
+	return tierNames[t]
+}
+
+// ParseTier returns the tier with the given name.
+func ParseTier(name string) (Tier, error) {
+	for i, tierName := range tierNames {
+		if name == tierName {
+			return Tier(i), nil
+		}
+	}
+	return TierCasual, fmt.Errorf("unknown logic tier: %s", name)
+}
+
 // A Node is a mapping of strings that will become And or Or nodes in the
 // graph. A node can have nested nodes as parents instead of strings.
 type Node struct {
 	Parents []interface{}
 	Type    Type
+	Tier    Tier   // minimum tier at which the node is usable
+	Trick   string // name for enabling or disabling the node by settings
 }
 
 // CreateFunc returns a function that creates graph nodes from a list of key
 // strings or sub-nodes, based on the given node type.
 func CreateFunc(nodeType Type) func(parents ...interface{}) *Node {
+	return CreateTierFunc(nodeType, TierCasual)
+}
+
+// CreateTierFunc is like CreateFunc, but the created nodes are only usable at
+// the given tier or above.
+func CreateTierFunc(nodeType Type,
+	tier Tier) func(parents ...interface{}) *Node {
 	return func(parents ...interface{}) *Node {
-		return &Node{Parents: parents, Type: nodeType}
+		return &Node{Parents: parents, Type: nodeType, Tier: tier}
 	}
 }
 
@@ -64,6 +103,10 @@ var (
 	Hard    = CreateFunc(HardAndType) // for wrapping single nodes
 	HardAnd = CreateFunc(HardAndType)
 	HardOr  = CreateFunc(HardOrType)
+
+	Medium    = CreateTierFunc(AndType, TierMedium) // for wrapping single nodes
+	MediumAnd = CreateTierFunc(AndType, TierMedium)
+	MediumOr  = CreateTierFunc(OrType, TierMedium)
 )
 
 var seasonsNodes, agesNodes map[string]*Node
@@ -116,14 +159,64 @@ func SeasonsExtraItems() map[string]*Node {
 	return copyMap(seasonsBaseItemNodes)
 }
 
-// GetSeasons returns a copy of all seasons nodes.
-func GetSeasons() map[string]*Node {
-	return copyMap(seasonsNodes)
+// Trick names a node so that it can be enabled or disabled by settings,
+// overriding its tier and hardness.
+func Trick(name string, node *Node) *Node {
+	node.Trick = name
+	return node
 }
 
-// GetAges returns a copy of all ages nodes.
-func GetAges() map[string]*Node {
-	return copyMap(agesNodes)
+// TrickNames returns the sorted names of all tricks in both games.
+func TrickNames() []string {
+	names, seen := make([]string, 0), make(map[string]bool)
+	for _, nodes := range []map[string]*Node{seasonsNodes, agesNodes} {
+		for _, pn := range nodes {
+			if pn.Trick != "" && !seen[pn.Trick] {
+				names = append(names, pn.Trick)
+				seen[pn.Trick] = true
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetSeasons returns a copy of all seasons nodes, filtered by tier and tricks.
+func GetSeasons(tier Tier, tricks map[string]bool) map[string]*Node {
+	return filterTier(copyMap(seasonsNodes), tier, tricks)
+}
+
+// GetAges returns a copy of all ages nodes, filtered by tier and tricks.
+func GetAges(tier Tier, tricks map[string]bool) map[string]*Node {
+	return filterTier(copyMap(agesNodes), tier, tricks)
+}
+
+// replaces nodes above the given tier and disabled tricks with parentless Or
+// nodes, which are never satisfied, so that nothing can be reached through
+// them. enabled tricks are replaced with plain nodes of the same type.
+func filterTier(nodes map[string]*Node,
+	tier Tier, tricks map[string]bool) map[string]*Node {
+	for name, pn := range nodes {
+		enabled, set := tricks[pn.Trick]
+		switch {
+		case pn.Trick != "" && set && enabled:
+			nodes[name] = &Node{Parents: pn.Parents, Type: softType(pn.Type)}
+		case pn.Trick != "" && set && !enabled, pn.Tier > tier:
+			nodes[name] = Or()
+		}
+	}
+	return nodes
+}
+
+// returns the non-hard equivalent of the node type.
+func softType(nodeType Type) Type {
+	switch nodeType {
+	case HardAndType:
+		return AndType
+	case HardOrType:
+		return OrType
+	}
+	return nodeType
 }
 
 // merge the given maps into the first argument
@@ -19,11 +19,11 @@ func isParent(p1Name string, p2 *Node) bool {
 
 func TestLinks(t *testing.T) {
 	// need to be changed manually for now
-	nodes := GetAges()
-	rom.Init(rom.GameAges)
+	nodes := GetAges(TierHard, nil)
+	rs := rom.NewState(rom.GameAges)
 
-	for key, slot := range rom.ItemSlots {
-		treasureName := rom.FindTreasureName(slot.Treasure)
+	for key, slot := range rs.ItemSlots {
+		treasureName := rs.FindTreasureName(slot.Treasure)
 		if node, ok := nodes[treasureName]; ok {
 			node.Parents = append(node.Parents, key)
 		} else {
@@ -67,3 +67,48 @@ func TestLinks(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterTier(t *testing.T) {
+	nodes := map[string]*Node{
+		"a": And("b", Medium("c")),
+		"b": Medium("c"),
+		"c": And(),
+	}
+	flattenNestedNodes(nodes)
+
+	filterTier(nodes, TierMedium, nil)
+	if len(nodes["a 1"].Parents) != 1 {
+		t.Errorf("medium node filtered at medium tier")
+	}
+	filterTier(nodes, TierCasual, nil)
+	if nodes["b"].Type != OrType || len(nodes["b"].Parents) != 0 {
+		t.Errorf("medium node not filtered at casual tier")
+	}
+
+	// tricks override tiers in both directions.
+	nodes = map[string]*Node{
+		"a": Trick("a", HardAnd("c")),
+		"b": Trick("b", And("c")),
+		"c": And(),
+	}
+	filterTier(nodes, TierCasual, map[string]bool{"a": true, "b": false})
+	if nodes["a"].Type != AndType || len(nodes["a"].Parents) != 1 {
+		t.Errorf("enabled trick not made soft")
+	}
+	if len(nodes["b"].Parents) != 0 {
+		t.Errorf("disabled trick not filtered")
+	}
+
+	// bomb jumps are medium tricks.
+	if len(GetSeasons(TierCasual, nil)["bomb jump 2 1"].Parents) != 0 ||
+		len(GetSeasons(TierMedium, nil)["bomb jump 2 1"].Parents) != 2 {
+		t.Errorf("bomb jump not filtered by medium tier")
+	}
+
+	if tier, err := ParseTier("hard"); err != nil || tier != TierHard {
+		t.Errorf("ParseTier(\"hard\") = %v, %v", tier, err)
+	}
+	if _, err := ParseTier("easy"); err == nil {
+		t.Errorf("expected error for unknown tier")
+	}
+}
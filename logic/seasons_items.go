@@ -37,20 +37,27 @@ var seasonsItemNodes = map[string]*Node{
 
 	// this of course doesn't apply to all trees, but trees won't have any
 	// seeds attached to them unless they can be harvested. so it works out.
+	//
+	// getting seeds from anywhere but a tree is tagged as the "seed-refills"
+	// trick, so that settings can decide whether the distance to a tree
+	// matters, independently of the logic tier.
 	"refill seeds": Or("harvest tree", "dimitri's flute", "dimitri",
-		Hard("remove bush")),
+		Trick("seed-refills", Hard("remove bush"))),
 
 	"harvest ember seeds": And("seed item", Or(
-		And("ember tree seeds", "refill seeds"), Hard("d5 armos chest"),
-		HardAnd("harvest bush", Or("enter agunima", "enter d7")))),
+		And("ember tree seeds", "refill seeds"),
+		Trick("seed-refills", Hard("d5 armos chest")),
+		Trick("seed-refills",
+			HardAnd("harvest bush", Or("enter agunima", "enter d7"))))),
 	"harvest mystery seeds": And("seed item", Or(
 		And("mystery tree seeds", "refill seeds"),
-		HardAnd("d8 armos chest", "harvest bush"))),
+		Trick("seed-refills", HardAnd("d8 armos chest", "harvest bush")))),
 	"harvest scent seeds": And("scent tree seeds",
 		"seed item", "refill seeds"),
 	"harvest pegasus seeds": And("seed item", Or(
 		And("pegasus tree seeds", "refill seeds"),
-		HardAnd("beach", "shield", "ore chunks", "seed item"))), // market
+		Trick("seed-refills", HardAnd("beach", "shield", "ore chunks",
+			"seed item")))), // market
 	"harvest gale seeds": And("gale tree seeds",
 		"seed item", "refill seeds"),
 
@@ -62,6 +69,21 @@ var seasonsItemNodes = map[string]*Node{
 	"any satchel": Or("ember satchel", "mystery satchel", "scent satchel",
 		"pegasus satchel", "gale satchel"),
 
+	// gale seeds in the satchel warp to any tree that's been visited. it's a
+	// trick so that settings can keep progression from depending on warps.
+	"gale warp": Trick("gale-warp", And("gale satchel", Or(
+		"horon village tree visited", "woods of winter tree visited",
+		"north horon tree visited", "spool swamp tree visited",
+		"sunken city tree visited", "tarm ruins tree visited"))),
+	"horon village tree visited":   And("horon village"),
+	"woods of winter tree visited": And("central woods of winter"),
+	"north horon tree visited":     And("blaino's gym"),
+	"spool swamp tree visited":     And("north swamp"),
+	"sunken city tree visited": And("sunken city",
+		Or("jump 2", "flippers", "dimitri's flute",
+			"sunken city default winter")),
+	"tarm ruins tree visited": And("lost woods"),
+
 	"ember slingshot":   And("harvest ember seeds", "slingshot"),
 	"mystery slingshot": And("harvest mystery seeds", "slingshot"),
 	"scent slingshot":   And("harvest scent seeds", "slingshot"),
@@ -100,10 +122,10 @@ var seasonsItemNodes = map[string]*Node{
 	// jump x pit tiles
 	"jump 2":      Or("feather L-1", "feather L-2"),
 	"jump 3":      Or(And("feather L-1", "pegasus satchel"), "feather L-2"),
-	"bomb jump 2": Or("jump 3", HardAnd("jump 2", "bombs")),
-	"bomb jump 3": Or("jump 4", HardAnd("jump 3", "bombs")),
+	"bomb jump 2": Or("jump 3", MediumAnd("jump 2", "bombs")),
+	"bomb jump 3": Or("jump 4", MediumAnd("jump 3", "bombs")),
 	"jump 4":      And("feather L-2"),
-	"bomb jump 4": Or("jump 6", HardAnd("jump 4", "bombs")),
+	"bomb jump 4": Or("jump 6", MediumAnd("jump 4", "bombs")),
 	"jump 6":      And("feather L-2", "pegasus satchel"),
 
 	"harvest tree": Or("sword", "rod", "fool's ore"),
@@ -1,34 +1,28 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/randomizer"
 	"github.com/jangler/oracles-randomizer/rom"
 	"github.com/jangler/oracles-randomizer/ui"
 )
 
 type logFunc func(string, ...interface{})
 
-// gameName returns the short name associated with a game number.
-func gameName(game int) string {
-	switch game {
-	case rom.GameAges:
-		return "ooa"
-	case rom.GameSeasons:
-		return "oos"
-	default:
-		return "UNKNOWN"
-	}
-}
-
 // usage is called when an invalid CLI invocation is used, or if the -h flag is
 // passed.
 func usage() {
@@ -42,43 +36,200 @@ func fatal(err error, logf logFunc) {
 	logf("fatal: %v.", err)
 }
 
+// stringList is a flag.Value that collects the values of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, "; ")
+}
+
+func (l *stringList) Get() interface{} {
+	return append([]string{}, *l...)
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
 // options specified on the command line or via the TUI
 var (
+	flagAccess   string
+	flagAnimal   string
+	flagBias     string
+	flagCompass  bool
+	flagDaily    string
+	flagDump     bool
+	flagDryRun   string
+	flagDungeon  int
+	flagDupSeeds bool
+	flagEarly    bool
+	flagExport   string
+	flagForward  bool
+	flagFree     bool
+	flagGale     string
+	flagGraph    string
+	flagGraphFmt string
 	flagHard     bool
+	flagHearts   int
+	flagInspect  bool
+	flagJunk     stringList
+	flagKeep     stringList
+	flagList     bool
+	flagLicensee string
+	flagLogic    string
+	flagMapHints int
+	flagMemMap   bool
 	flagN        int
+	flagNoMaps   bool
 	flagNoMusic  bool
 	flagNoUI     bool
+	flagPalette  string
+	flagPreset   string
+	flagSave     string
 	flagSeed     string
+	flagStart    stringList
+	flagStartEmb bool
 	flagStats    string
+	flagTitle    string
+	flagTrees    stringList
 	flagTreewarp bool
+	flagTricks   string
+	flagVanilla  int
 	flagVerbose  bool
+	flagVerify   bool
+	flagWorkers  int
 )
 
 // initFlags initializes the CLI/TUI option values and variables.
 func initFlags() {
 	flag.Usage = usage
+	flag.StringVar(&flagAccess, "access", "all",
+		"which slots must be reachable: all, or only enough to be beatable")
+	flag.StringVar(&flagAnimal, "companion", "random",
+		"animal companion: ricky, dimitri, moosh, or random")
+	flag.StringVar(&flagBias, "bias", "none",
+		"favor dungeon or overworld slots for progression, or none")
+	flag.BoolVar(&flagCompass, "compass-hints", false,
+		"make the compass beep for all progression items, not just boss keys")
+	flag.StringVar(&flagDaily, "daily", "",
+		"use the seed of the day (UTC) for the given community salt")
+	flag.BoolVar(&flagDump, "dump", false,
+		"print the treasure table and slot contents of a ROM")
+	flag.StringVar(&flagDryRun, "dry-run", "",
+		"print a spoiler log for 'seasons' or 'ages' without using a ROM")
+	flag.IntVar(&flagDungeon, "dungeon-max", 0,
+		"place at most this many progression items in each dungeon, or 0 "+
+			"for no limit")
+	flag.BoolVar(&flagDupSeeds, "dup-seeds", false,
+		"let extra seed trees grow any seed type, even one already duplicated")
+	flag.BoolVar(&flagEarly, "early-weapon", false,
+		"place a sword, bombs, or rod where it can be reached with no items")
+	flag.StringVar(&flagExport, "export-tracker", "",
+		"print a JSON tracker package for 'seasons' or 'ages'")
+	flag.StringVar(&flagGraph, "export-graph", "",
+		"print the logic graph for 'seasons' or 'ages', with the placements "+
+			"for -seed or -daily if given")
+	flag.StringVar(&flagGraphFmt, "graph-format", "dot",
+		"format for -export-graph: dot or graphml")
+	flag.BoolVar(&flagForward, "forward-fill", false,
+		"place items with the old forward fill, which tends to put "+
+			"progression early")
+	flag.BoolVar(&flagFree, "freespace", false,
+		"print regions of a ROM that appear to be unused")
+	flag.StringVar(&flagGale, "gale-warp", "progression",
+		"whether logic can require warping with gale seeds: progression "+
+			"or convenience (seasons only)")
 	flag.BoolVar(&flagHard, "hard", false,
-		"require some plays outside normal logic")
+		"same as -logic hard")
+	flag.BoolVar(&flagInspect, "inspect", false,
+		"print the version, seed, and settings of a randomized ROM")
+	flag.IntVar(&flagHearts, "starting-hearts", rom.VanillaHearts,
+		"number of hearts to start a new file with")
+	flag.BoolVar(&flagList, "list-presets", false,
+		"print the built-in presets and the flags they set")
+	flag.Var(&flagJunk, "junk",
+		`weight filler items, e.g. "rupees, 100=5" or "gasha seed=0"; `+
+			"can be given more than once")
+	flag.Var(&flagKeep, "keep-cutscene",
+		"play a cutscene that's normally skipped (can be given more than "+
+			"once); seasons: "+strings.Join(rom.SkipNames(rom.GameSeasons),
+			", ")+"; ages: "+strings.Join(rom.SkipNames(rom.GameAges), ", "))
+	flag.StringVar(&flagLicensee, "licensee", "",
+		"old licensee code to write to the ROM header (hex byte)")
+	flag.StringVar(&flagLogic, "logic", "casual",
+		"logic tier: casual, medium, or hard")
+	flag.IntVar(&flagMapHints, "map-hints", 0,
+		"number of treasure map sparkles to use for progression items "+
+			"(seasons only); each clears when its jewel is found, not the "+
+			"hinted item")
+	flag.BoolVar(&flagMemMap, "memory-map", false,
+		"also write a JSON file of RAM flags for auto-trackers")
 	flag.IntVar(&flagN, "n", 100,
 		"number of trials for stats")
 	flag.BoolVar(&flagNoMusic, "nomusic", false,
 		"don't play any music in the modified ROM")
+	flag.BoolVar(&flagNoMaps, "nomaps", false,
+		"replace dungeon maps and compasses with filler items")
 	flag.BoolVar(&flagNoUI, "noui", false,
 		"use command line output without option prompts")
+	flag.StringVar(&flagPalette, "palette", "random",
+		"tunic color: green, blue, red, gold, or random")
+	flag.StringVar(&flagPreset, "preset", "",
+		"use flags from a built-in preset or a .json preset file; "+
+			"other flags override it")
+	flag.StringVar(&flagSave, "save-preset", "",
+		"write the given randomization flags to a .json preset file")
 	flag.StringVar(&flagSeed, "seed", "",
 		"specific random seed to use (32-bit hex number)")
+	flag.Var(&flagStart, "start-item",
+		"start with the named item (can be given more than once)")
+	flag.BoolVar(&flagStartEmb, "start-ember", false,
+		"grow ember seeds on the starting village's seed tree")
 	flag.StringVar(&flagStats, "stats", "",
 		"test routes and print stats for 'seasons' or 'ages'")
+	flag.StringVar(&flagTitle, "title", "",
+		"title to write to the ROM header, like 'OOS RANDO' (up to 11 "+
+			"characters)")
+	flag.Var(&flagTrees, "tree",
+		"grow seeds on a tree, as 'tree name=seed type' (can be given more "+
+			"than once)")
 	flag.BoolVar(&flagTreewarp, "treewarp", false,
 		"warp to ember tree by pressing start+B on map screen")
+	flag.StringVar(&flagTricks, "tricks", "",
+		"JSON file of trick names to enable (true) or disable (false)")
+	flag.IntVar(&flagVanilla, "vanilla", 0,
+		"percent chance for each slot to keep its vanilla item")
 	flag.BoolVar(&flagVerbose, "verbose", false,
 		"print more detailed output to terminal")
+	flag.BoolVar(&flagVerify, "verify", false,
+		"print a report on the contents of a randomized ROM")
+	flag.IntVar(&flagWorkers, "workers", 1,
+		"number of placement attempts to run at once")
 	flag.Parse()
 }
 
 // main is the program's entry point.
 func main() {
 	initFlags()
+	if err := checkOptions(); err != nil {
+		fmt.Printf("fatal: %v.\n", err)
+		return
+	}
+	if flagPreset != "" {
+		// check again, since the preset can set flags with invalid values.
+		p, err := loadPreset(flagPreset)
+		if err == nil {
+			err = applyPreset(p)
+		}
+		if err == nil {
+			err = checkOptions()
+		}
+		if err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+			return
+		}
+	}
 
 	if flagStats != "" {
 		// do stats instead of randomizing
@@ -93,12 +244,138 @@ func main() {
 			return
 		}
 
-		rom.Init(game)
+		tier, err := logicTier()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		tricks, err := loadTricks(flagTricks)
+		if err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+			return
+		}
+
 		rand.Seed(time.Now().UnixNano())
-		logStats(game, flagN, flagHard, func(s string, a ...interface{}) {
-			fmt.Printf(s, a...)
-			fmt.Println()
-		})
+		randomizer.LogStats(game, flagN, tier, tricks,
+			func(s string, a ...interface{}) {
+				fmt.Printf(s, a...)
+				fmt.Println()
+			})
+	} else if flagExport != "" {
+		// print tracker data instead of randomizing
+		var game int
+
+		if flagExport == "seasons" {
+			game = rom.GameSeasons
+		} else if flagExport == "ages" {
+			game = rom.GameAges
+		} else {
+			fmt.Printf("'%s' is invalid. try 'seasons' or 'ages'.\n", flagExport)
+			return
+		}
+
+		tier, err := logicTier()
+		if err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+			return
+		}
+		tricks, err := loadTricks(flagTricks)
+		if err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+			return
+		}
+
+		b, err := randomizer.ExportTracker(game, tier, tricks)
+		if err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+			return
+		}
+		fmt.Println(string(b))
+	} else if flagGraph != "" {
+		// print the logic graph instead of randomizing
+		var game int
+
+		if flagGraph == "seasons" {
+			game = rom.GameSeasons
+		} else if flagGraph == "ages" {
+			game = rom.GameAges
+		} else {
+			fmt.Printf("'%s' is invalid. try 'seasons' or 'ages'.\n", flagGraph)
+			return
+		}
+
+		// progress goes to stderr so that stdout is only the graph.
+		logf := func(s string, a ...interface{}) {
+			fmt.Fprintf(os.Stderr, s, a...)
+			fmt.Fprintln(os.Stderr)
+		}
+		placed := flagSeed != "" || flagDaily != ""
+		opts, err := randomizeOptions(game, logf)
+		if err != nil {
+			fatal(err, logf)
+			return
+		}
+		b, err := randomizer.ExportGraph(context.Background(), game, opts,
+			flagGraphFmt, placed)
+		if err != nil {
+			fatal(err, logf)
+			return
+		}
+		os.Stdout.Write(b)
+	} else if flagDryRun != "" {
+		// route and print the spoiler log without reading or writing a ROM
+		var game int
+
+		if flagDryRun == "seasons" {
+			game = rom.GameSeasons
+		} else if flagDryRun == "ages" {
+			game = rom.GameAges
+		} else {
+			fmt.Printf("'%s' is invalid. try 'seasons' or 'ages'.\n", flagDryRun)
+			return
+		}
+
+		// progress goes to stderr so that stdout is only the log.
+		logf := func(s string, a ...interface{}) {
+			fmt.Fprintf(os.Stderr, s, a...)
+			fmt.Fprintln(os.Stderr)
+		}
+		opts, err := randomizeOptions(game, logf)
+		if err != nil {
+			fatal(err, logf)
+			return
+		}
+		res, err := randomizer.DryRun(context.Background(), game, opts)
+		if err != nil {
+			fatal(err, logf)
+			return
+		}
+		fmt.Print(res.Spoiler)
+	} else if flagList {
+		listPresets()
+	} else if flagSave != "" {
+		if err := savePreset(flagSave); err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+			return
+		}
+		fmt.Printf("wrote preset to %s\n", flagSave)
+	} else if flagVerify || flagDump || flagFree || flagInspect {
+		// report on an existing ROM instead of randomizing
+		if flag.NArg() != 1 {
+			flag.Usage()
+			return
+		}
+		report := verifyReport
+		if flagDump {
+			report = rom.DumpTables
+		} else if flagFree {
+			report = rom.FreeSpaceReport
+		} else if flagInspect {
+			report = inspectReport
+		}
+		if err := reportROM(flag.Arg(0), report); err != nil {
+			fmt.Printf("fatal: %v.\n", err)
+		}
 	} else if flag.NArg()+flag.NFlag() > 1 { // CLI used
 		// run randomizer on main goroutine
 		runRandomizer(false, func(s string, a ...interface{}) {
@@ -107,7 +384,7 @@ func main() {
 		})
 	} else { // CLI maybe not used
 		// run TUI on main goroutine and randomizer on alternate goroutine
-		ui.Init("oracles randomizer " + version)
+		ui.Init("oracles randomizer " + randomizer.Version)
 		go runRandomizer(true, func(s string, a ...interface{}) {
 			ui.Printf(s, a...)
 		})
@@ -180,8 +457,6 @@ func runRandomizer(useTUI bool, logf logFunc) {
 		if err != nil {
 			fatal(err, logf)
 			return
-		} else {
-			rom.Init(game)
 		}
 		logf("randomizing %s.", infile)
 
@@ -191,20 +466,113 @@ func runRandomizer(useTUI bool, logf logFunc) {
 			logf("")
 		}
 
-		rom.SetMusic(!flagNoMusic)
-		rom.SetTreewarp(flagTreewarp)
-
-		if err := randomizeFile(b, game, dirName, outfile, flagSeed,
-			flagHard, flagVerbose, logf); err != nil {
+		opts, err := randomizeOptions(game, logf)
+		if err != nil {
+			fatal(err, logf)
+			return
+		}
+		if err := randomizeFile(b, game, dirName, outfile, opts,
+			logf); err != nil {
 			fatal(err, logf)
 			return
 		}
+		if flagMemMap {
+			if err := writeMemoryMap(dirName, game, logf); err != nil {
+				fatal(err, logf)
+				return
+			}
+		}
+	}
+}
+
+// randomizeOptions returns the options for randomizing the game given by the
+// command line, and picks the seed if none was given.
+func randomizeOptions(game int,
+	logf logFunc) (randomizer.Options, error) {
+	if flagDaily != "" {
+		if flagSeed != "" {
+			return randomizer.Options{},
+				fmt.Errorf("-daily and -seed can't be used together")
+		}
+		flagSeed = fmt.Sprintf("%08x", dailySeed(time.Now(), flagDaily))
+		logf("using seed of the day %s.", flagSeed)
+	}
+
+	seed, err := setRandomSeed(flagSeed)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	tier, err := logicTier()
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	tricks, err := loadTricks(flagTricks)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	trees, err := parseTrees(game, flagTrees, flagStartEmb)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	licensee, err := parseLicensee(flagLicensee)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	companion, err := randomizer.ParseCompanion(flagAnimal)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	bias, err := randomizer.ParseBias(flagBias)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+	junk, err := parseJunk(flagJunk)
+	if err != nil {
+		return randomizer.Options{}, err
 	}
+	access, err := randomizer.ParseAccess(flagAccess)
+	if err != nil {
+		return randomizer.Options{}, err
+	}
+
+	return randomizer.Options{
+		Seed:           seed,
+		Tier:           tier,
+		Tricks:         tricks,
+		VanillaPercent: flagVanilla,
+		StartItems:     flagStart,
+		DupSeeds:       flagDupSeeds,
+		FixedTrees:     trees,
+		RemoveMaps:     flagNoMaps,
+		MapHints:       flagMapHints,
+		CompassHints:   flagCompass,
+		StartingHearts: flagHearts,
+		NoMusic:        flagNoMusic,
+		Treewarp:       flagTreewarp,
+		KeepCutscenes:  flagKeep,
+		Companion:      companion,
+		ForwardFill:    flagForward,
+		Bias:           bias,
+		DungeonMax:     flagDungeon,
+		JunkWeights:    junk,
+		EarlyWeapon:    flagEarly,
+		Access:         access,
+		Palette:        flagPalette,
+		Title:          flagTitle,
+		Licensee:       licensee,
+		Workers:        flagWorkers,
+		Verbose:        flagVerbose,
+		Log:            logf,
+	}, nil
 }
 
 // getAndLogOptions logs values of selected options, prompting for them first
 // if the TUI is used.
 func getAndLogOptions(useTUI bool, logf logFunc) {
+	if flagPreset != "" {
+		logf("using preset %s.", flagPreset)
+	}
+
 	if useTUI {
 		if ui.Prompt("use specific seed? (y/n)") == 'y' {
 			flagSeed = ui.PromptSeed("enter seed: (8-digit hex number)")
@@ -215,10 +583,8 @@ func getAndLogOptions(useTUI bool, logf logFunc) {
 	if useTUI {
 		flagHard = ui.Prompt("enable hard difficulty? (y/n)") == 'y'
 	}
-	if flagHard {
-		logf("using hard difficulty.")
-	} else {
-		logf("using normal difficulty.")
+	if tier, err := logicTier(); err == nil {
+		logf("using %s logic.", tier)
 	}
 
 	if useTUI {
@@ -238,6 +604,71 @@ func getAndLogOptions(useTUI bool, logf logFunc) {
 	} else {
 		logf("tree warp off.")
 	}
+
+	logf("using %s palette.", flagPalette)
+	if flagVanilla > 0 {
+		logf("%d%% of slots keep vanilla items.", flagVanilla)
+	}
+	for _, name := range flagStart {
+		logf("starting with %s.", name)
+	}
+	if flagNoMaps {
+		logf("dungeon maps and compasses removed.")
+	}
+	if flagCompass {
+		logf("compass beeps for progression items.")
+	}
+	if flagMapHints > 0 {
+		logf("treasure map marks %d progression items.", flagMapHints)
+	}
+	if flagWorkers > 1 {
+		logf("running %d placement attempts at once.", flagWorkers)
+	}
+	if flagMemMap {
+		logf("writing memory map for auto-trackers.")
+	}
+	for _, name := range flagKeep {
+		logf("keeping %s cutscene.", name)
+	}
+	if flagTitle != "" {
+		logf("ROM title %s.", flagTitle)
+	}
+	if flagLicensee != "" {
+		logf("ROM licensee %s.", flagLicensee)
+	}
+	if flagHearts != rom.VanillaHearts {
+		logf("starting with %d hearts.", flagHearts)
+	}
+	if flagDupSeeds {
+		logf("extra seed trees can duplicate any seed type.")
+	}
+	if flagAnimal != "random" {
+		logf("animal companion is %s.", flagAnimal)
+	}
+	if flagForward {
+		logf("using forward fill.")
+	}
+	if flagBias != "none" {
+		logf("progression favors %s slots.", flagBias)
+	}
+	if flagDungeon > 0 {
+		logf("at most %d progression items per dungeon.", flagDungeon)
+	}
+	for _, spec := range flagJunk {
+		logf("junk weight %s.", spec)
+	}
+	if flagEarly {
+		logf("a weapon can be reached with no items.")
+	}
+	if flagAccess != "all" {
+		logf("some slots may be unreachable.")
+	}
+	if flagStartEmb {
+		logf("starting seed tree grows ember seeds.")
+	}
+	for _, spec := range flagTrees {
+		logf("seed tree %s.", spec)
+	}
 }
 
 // attempt to write rom data to a file and print summary info.
@@ -355,170 +786,245 @@ func readGivenROM(filename string) ([]byte, int, error) {
 	return b, game, nil
 }
 
-func randomizeFile(romData []byte, game int, dirName, outfile, seedFlag string,
-	hard, verbose bool, logf logFunc) error {
-	var seed uint32
-	var sum []byte
-	var err error
-	var logFilename string
-
-	// operate on rom data
-	if outfile != "" {
-		logFilename = outfile[:len(outfile)-4] + "_log.txt"
-	}
-	seed, sum, logFilename, err = randomize(
-		romData, game, dirName, logFilename, seedFlag, hard, verbose, logf)
+// reportROM prints the text returned by the report function for the given
+// ROM file, which need not be vanilla.
+func reportROM(filename string,
+	report func(b []byte, game int) string) error {
+	b, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
-	hardString := ""
-	if hard {
-		hardString = "_hard"
+
+	if !rom.IsAges(b) && !rom.IsSeasons(b) {
+		return fmt.Errorf("%s is not an oracles ROM", filename)
 	}
-	if outfile == "" {
-		outfile = fmt.Sprintf("%srando_%s_%08x%s.gbc",
-			gameName(game), version, seed, hardString)
+	game := rom.GameAges
+	if rom.IsSeasons(b) {
+		game = rom.GameSeasons
 	}
 
-	// write to file
-	return writeROM(romData, dirName, outfile, logFilename, seed, sum, logf)
+	fmt.Print(report(b, game))
+
+	return nil
 }
 
-// setRandomSeed sets a 32-bit unsigned random seed based on a hexstring, if
-// non-empty, or else the current time, and returns that seed.
-func setRandomSeed(hexString string) (uint32, error) {
-	seed := uint32(time.Now().UnixNano())
-	if hexString != "" {
-		v, err := strconv.ParseUint(
-			strings.Replace(hexString, "0x", "", 1), 16, 32)
-		if err != nil {
-			return 0, fmt.Errorf(`invalid seed "%s"`, hexString)
-		}
-		seed = uint32(v)
+// verifyReport describes a randomized ROM's contents, so that bad seeds can be
+// debugged without their log files.
+func verifyReport(b []byte, game int) string {
+	s := fmt.Sprintf("game: %s\n", randomizer.GameName(game))
+	if rom.IsVanilla(b) {
+		s += "ROM is vanilla.\n"
 	}
-	rand.Seed(int64(seed))
-
-	return seed, nil
+	return s + rom.MakeReport(b, game).String()
 }
 
-// messes up rom data and writes it to a file.
-func randomize(romData []byte, game int, dirName, logFilename, seedFlag string,
-	hard, verbose bool, logf logFunc) (uint32, []byte, string, error) {
-	// sanity check beforehand
-	if errs := rom.Verify(romData, game); errs != nil {
-		if verbose {
-			for _, err := range errs {
-				logf(err.Error())
-			}
-		}
-		return 0, nil, "", errs[0]
+// inspectReport describes the randomizer version, seed, and settings that a
+// ROM was made with, according to its info block.
+func inspectReport(b []byte, game int) string {
+	s := fmt.Sprintf("game: %s\n", randomizer.GameName(game))
+	info, ok := rom.ReadInfo(b)
+	if !ok {
+		return s + "no randomizer info; the ROM is vanilla or from an " +
+			"older version.\n"
 	}
+	return s + info + "\n"
+}
 
-	seed, err := setRandomSeed(seedFlag)
+func randomizeFile(romData []byte, game int, dirName, outfile string,
+	opts randomizer.Options, logf logFunc) error {
+	res, err := randomizer.Generate(context.Background(), romData, opts)
 	if err != nil {
-		return 0, nil, "", err
+		return err
 	}
 
-	// search for route
-	ri := findRoute(game, seed, hard, verbose, logf)
-	if ri == nil {
-		return 0, nil, "", fmt.Errorf("no route found")
+	var logFilename string
+	if outfile != "" {
+		logFilename = outfile[:len(outfile)-4] + "_log.txt"
+	} else {
+		base := fmt.Sprintf("%srando_%s_%08x",
+			randomizer.GameName(game), randomizer.Version, res.Seed)
+		if opts.Tier != logic.TierCasual {
+			outfile = fmt.Sprintf("%s_%s.gbc", base, opts.Tier)
+			logFilename = fmt.Sprintf("%s_%s_log.txt", base, opts.Tier)
+		} else {
+			outfile = base + ".gbc"
+			logFilename = base + "_log.txt"
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirName, logFilename),
+		[]byte(res.Spoiler), 0644); err != nil {
+		return err
 	}
 
-	checksum, err := setROMData(romData, game, ri, logf, verbose)
+	// write to file
+	return writeROM(res.ROM, dirName, outfile, logFilename, res.Seed, res.Sum,
+		logf)
+}
+
+// writeMemoryMap writes the JSON memory map of the game's slot and treasure
+// flags to a file. the map doesn't depend on the seed, so the filename only
+// has the game and version.
+func writeMemoryMap(dirName string, game int, logf logFunc) error {
+	filename := fmt.Sprintf("%srando_%s_memory.json",
+		randomizer.GameName(game), randomizer.Version)
+	b, err := json.MarshalIndent(rom.MakeMemoryMap(game), "", "\t")
 	if err != nil {
-		return 0, nil, "", err
+		return err
 	}
+	if err := ioutil.WriteFile(filepath.Join(dirName, filename), b,
+		0644); err != nil {
+		return err
+	}
+	logf("wrote memory map to %s", filename)
+	return nil
+}
 
-	hardString := ""
-	if hard {
-		hardString = "hard_"
+// logicTier returns the tier given by -logic, raised to hard if -hard is set.
+func logicTier() (logic.Tier, error) {
+	tier, err := logic.ParseTier(flagLogic)
+	if flagHard && tier < logic.TierHard {
+		tier = logic.TierHard
 	}
-	if logFilename == "" {
-		logFilename = fmt.Sprintf("%srando_%s_%08x_%slog.txt",
-			gameName(game), version, ri.Seed, hardString)
+	return tier, err
+}
+
+// loadTricks reads a JSON object mapping trick names to whether they're
+// enabled. tricks not in the file follow the logic tier.
+func loadTricks(filename string) (map[string]bool, error) {
+	if filename == "" {
+		return galeTricks(nil), nil
 	}
-	summary, summaryDone := getSummaryChannel(
-		filepath.Join(dirName, logFilename))
 
-	// write info to summary file
-	summary <- fmt.Sprintf("seed: %08x", ri.Seed)
-	summary <- fmt.Sprintf("sha-1 sum: %x", checksum)
-	if hard {
-		summary <- fmt.Sprintf("difficulty: hard")
-	} else {
-		summary <- fmt.Sprintf("difficulty: normal")
-	}
-	summary <- ""
-	summary <- ""
-	checks := getChecks(ri)
-	spheres := getSpheres(ri.Route.Graph, checks, hard)
-	summary <- "-- progression items --"
-	summary <- ""
-	logSpheres(summary, checks, spheres,
-		func(name string) bool { return !itemIsJunk(name) })
-	summary <- ""
-	summary <- "-- other items --"
-	summary <- ""
-	logSpheres(summary, checks, spheres, itemIsJunk)
-	if game == rom.GameSeasons {
-		summary <- ""
-		summary <- "default seasons:"
-		summary <- ""
-		for name, area := range rom.Seasons {
-			summary <- fmt.Sprintf("%-15s <- %s",
-				name[:len(name)-7], seasonsByID[int(area.New[0])])
-		}
-		summary <- ""
-		summary <- fmt.Sprintf("natzu region <- %s", []string{
-			"", "natzu prairie", "natzu river", "natzu wasteland",
-		}[ri.Companion])
-	} else {
-		summary <- ""
-		summary <- fmt.Sprintf("animal companion <- %s", []string{
-			"", "ricky", "dimitri", "moosh",
-		}[ri.Companion])
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	tricks := make(map[string]bool)
+	if err := json.Unmarshal(b, &tricks); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
 	}
 
-	close(summary)
-	<-summaryDone
+	known := logic.TrickNames()
+	for name := range tricks {
+		i := sort.SearchStrings(known, name)
+		if i == len(known) || known[i] != name {
+			return nil, fmt.Errorf("unknown trick %q; known tricks are: %s",
+				name, strings.Join(known, ", "))
+		}
+	}
 
-	return ri.Seed, checksum, logFilename, nil
+	return galeTricks(tricks), nil
 }
 
-// itemIsJunk returns true iff the item with the given name can never be
-// progression, regardless of context.
-func itemIsJunk(name string) bool {
-	switch rom.Treasures[name].ID() {
-	// heart refill, PoH, HC, ring, compass, dungeon map, gasha seed
-	case 0x29, 0x2a, 0x2b, 0x2d, 0x32, 0x33, 0x34:
-		return true
+// galeTricks disables the gale warp trick if -gale-warp is "convenience", so
+// that warps never count toward progression.
+func galeTricks(tricks map[string]bool) map[string]bool {
+	if flagGale != "convenience" {
+		return tricks
 	}
-	return false
+	if tricks == nil {
+		tricks = make(map[string]bool)
+	}
+	tricks["gale-warp"] = false
+	return tricks
 }
 
-// setROMData mutates the ROM data in-place based on the given route.
-func setROMData(romData []byte, game int, ri *RouteInfo, logf logFunc,
-	verbose bool) ([]byte, error) {
-	// place selected treasures in slots
-	checks := getChecks(ri)
-	for slot, item := range checks {
-		if verbose {
-			logf("%s <- %s", slot.Name, item.Name)
+// parseTrees reads -tree specs like "deku forest tree=gale" into a map of seed
+// tree names to seed item names. if startEmber is set, the starting village's
+// tree is also given ember seeds.
+func parseTrees(game int, specs []string,
+	startEmber bool) (map[string]string, error) {
+	trees := make(map[string]string)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf(`invalid tree "%s"; use "tree name=seed type"`,
+				spec)
+		}
+		tree := strings.TrimSpace(parts[0])
+		seed := strings.TrimSpace(parts[1])
+		if !strings.HasSuffix(seed, " tree seeds") {
+			seed += " tree seeds"
+		}
+		if _, ok := trees[tree]; ok {
+			return nil, fmt.Errorf("duplicate tree: %s", tree)
+		}
+		trees[tree] = seed
+	}
+
+	if startEmber {
+		tree := "horon village seed tree"
+		if game == rom.GameAges {
+			tree = "south lynna tree"
+		}
+		if seed, ok := trees[tree]; ok && seed != "ember tree seeds" {
+			return nil, fmt.Errorf("-start-ember conflicts with %s=%s",
+				tree, seed)
 		}
-		rom.ItemSlots[slot.Name].Treasure = rom.Treasures[item.Name]
+		trees[tree] = "ember tree seeds"
 	}
 
-	// set season data
-	if game == rom.GameSeasons {
-		for area, id := range ri.Seasons {
-			rom.Seasons[fmt.Sprintf("%s season", area)].New = []byte{id}
+	return trees, nil
+}
+
+// parseJunk reads -junk values of the form "item name=weight".
+func parseJunk(specs []string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i == -1 {
+			return nil, fmt.Errorf(
+				`invalid junk weight "%s"; use "item name=weight"`, spec)
 		}
+		name := strings.TrimSpace(spec[:i])
+		weight, err := strconv.Atoi(strings.TrimSpace(spec[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf(
+				`invalid junk weight "%s"; use "item name=weight"`, spec)
+		}
+		if _, ok := weights[name]; ok {
+			return nil, fmt.Errorf("duplicate junk item: %s", name)
+		}
+		weights[name] = weight
 	}
+	return weights, nil
+}
 
-	rom.SetAnimal(ri.Companion)
-	rom.SetTunicColor(ri.TunicColor)
+// parseLicensee reads a -licensee value as a byte. an empty string gives -1,
+// which keeps the vanilla code.
+func parseLicensee(s string) (int, error) {
+	if s == "" {
+		return -1, nil
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 8)
+	if err != nil {
+		return -1, fmt.Errorf(`invalid licensee "%s"; use a hex byte`, s)
+	}
+	return int(v), nil
+}
 
-	// do it! (but don't write anything)
-	return rom.Mutate(romData, game)
+// dailySeed returns a seed derived from the UTC date of the given time and a
+// salt, so that everyone using the same salt on the same day gets the same
+// seed.
+func dailySeed(t time.Time, salt string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(t.UTC().Format("2006-01-02") + " " + salt))
+	return h.Sum32()
+}
+
+// setRandomSeed sets a 32-bit unsigned random seed based on a hexstring, if
+// non-empty, or else the current time, and returns that seed.
+func setRandomSeed(hexString string) (uint32, error) {
+	seed := uint32(time.Now().UnixNano())
+	if hexString != "" {
+		v, err := strconv.ParseUint(
+			strings.Replace(hexString, "0x", "", 1), 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf(`invalid seed "%s"`, hexString)
+		}
+		seed = uint32(v)
+	}
+	rand.Seed(int64(seed))
+
+	return seed, nil
 }
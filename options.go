@@ -0,0 +1,304 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/randomizer"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// flags that only affect randomization, and are ignored by the other modes.
+var randomizeFlags = []string{"access", "bias", "companion", "compass-hints",
+	"daily", "dungeon-max", "dup-seeds", "early-weapon", "forward-fill",
+	"gale-warp", "hard", "junk", "keep-cutscene", "licensee", "logic",
+	"map-hints", "memory-map", "nomaps", "nomusic", "palette", "preset",
+	"seed", "start-ember", "start-item", "starting-hearts", "title", "tree",
+	"treewarp", "tricks", "vanilla", "workers"}
+
+// flags that switch the program out of randomizing, at most one of which can
+// be used.
+var modeFlags = []string{"stats", "export-tracker", "export-graph", "verify",
+	"dump", "freespace", "inspect", "list-presets", "save-preset", "dry-run"}
+
+// each rule returns an error if the options conflict, given the set of flag
+// names given on the command line.
+var optionRules = []func(set map[string]bool) error{
+	func(set map[string]bool) error {
+		if modes := setFlags(set, modeFlags); len(modes) > 1 {
+			return fmt.Errorf("%s can't be used together; choose one",
+				joinFlags(modes))
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if !set["stats"] && set["n"] {
+			return fmt.Errorf("-n only applies to -stats")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		// stats only uses the logic options.
+		if set["stats"] {
+			ignored := setFlags(set, randomizeFlags)
+			for _, name := range []string{"hard", "logic", "tricks"} {
+				ignored = removeString(ignored, name)
+			}
+			if len(ignored) > 0 {
+				return fmt.Errorf("-stats ignores %s",
+					joinFlags(ignored))
+			}
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		// tracker export only uses the logic options.
+		if set["export-tracker"] {
+			ignored := setFlags(set, randomizeFlags)
+			for _, name := range []string{"hard", "logic", "tricks"} {
+				ignored = removeString(ignored, name)
+			}
+			if len(ignored) > 0 {
+				return fmt.Errorf("-export-tracker ignores %s",
+					joinFlags(ignored))
+			}
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if !set["export-graph"] && set["graph-format"] {
+			return fmt.Errorf("-graph-format only applies to -export-graph")
+		}
+		if !containsString(randomizer.GraphFormats, flagGraphFmt) {
+			return fmt.Errorf("-graph-format must be %s",
+				strings.Join(randomizer.GraphFormats, " or "))
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		// without a seed, graph export only uses the logic options.
+		if set["export-graph"] && !set["seed"] && !set["daily"] {
+			ignored := setFlags(set, randomizeFlags)
+			for _, name := range []string{"hard", "logic", "tricks",
+				"start-item"} {
+				ignored = removeString(ignored, name)
+			}
+			if len(ignored) > 0 {
+				return fmt.Errorf("-export-graph ignores %s without -seed "+
+					"or -daily", joinFlags(ignored))
+			}
+		}
+		if set["export-graph"] && set["memory-map"] {
+			return fmt.Errorf("-export-graph doesn't write files; " +
+				"remove -memory-map")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		for _, mode := range []string{"verify", "dump", "freespace",
+			"inspect"} {
+			ignored := setFlags(set, randomizeFlags)
+			if set[mode] && len(ignored) > 0 {
+				return fmt.Errorf("-%s reads an existing ROM and ignores %s",
+					mode, joinFlags(ignored))
+			}
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if set["dry-run"] && set["memory-map"] {
+			return fmt.Errorf("-dry-run doesn't write files; " +
+				"remove -memory-map")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if set["list-presets"] {
+			if ignored := setFlags(set, randomizeFlags); len(ignored) > 0 {
+				return fmt.Errorf("-list-presets ignores %s",
+					joinFlags(ignored))
+			}
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if set["save-preset"] {
+			if ignored := setFlags(set, unpresetFlags); len(ignored) > 0 {
+				return fmt.Errorf("-save-preset ignores %s",
+					joinFlags(ignored))
+			}
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if set["daily"] && set["seed"] {
+			return fmt.Errorf("-daily and -seed can't be used together; " +
+				"the seed of the day replaces -seed")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		tier, err := logic.ParseTier(flagLogic)
+		if err != nil {
+			return err
+		}
+		if set["hard"] && set["logic"] && tier < logic.TierHard {
+			return fmt.Errorf("-hard conflicts with -logic %s; "+
+				"use only -logic", tier)
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if flagVanilla < 0 || flagVanilla > 100 {
+			return fmt.Errorf("-vanilla must be from 0 to 100")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if flagHearts < rom.VanillaHearts || flagHearts > rom.MaxHearts {
+			return fmt.Errorf("-starting-hearts must be from %d to %d",
+				rom.VanillaHearts, rom.MaxHearts)
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if flagMapHints < 0 || flagMapHints > rom.TreasureMapSparkles {
+			return fmt.Errorf("-map-hints must be from 0 to %d",
+				rom.TreasureMapSparkles)
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		_, err := parseLicensee(flagLicensee)
+		return err
+	},
+	func(set map[string]bool) error {
+		weights, err := parseJunk(flagJunk)
+		if err != nil || len(weights) == 0 {
+			return err
+		}
+
+		// the game isn't known until the ROM is read, unless a mode names
+		// it, so only reject weights that can't work for either game.
+		games := []int{rom.GameSeasons, rom.GameAges}
+		for _, name := range []string{flagDryRun, flagGraph} {
+			switch name {
+			case "seasons":
+				games = games[:1]
+			case "ages":
+				games = games[1:]
+			}
+		}
+		for _, game := range games {
+			if err = randomizer.CheckJunkWeights(game, weights); err == nil {
+				return nil
+			}
+		}
+		return err
+	},
+	func(set map[string]bool) error {
+		_, err := randomizer.ParseCompanion(flagAnimal)
+		return err
+	},
+	func(set map[string]bool) error {
+		_, err := randomizer.ParseBias(flagBias)
+		return err
+	},
+	func(set map[string]bool) error {
+		_, err := randomizer.ParseAccess(flagAccess)
+		return err
+	},
+	func(set map[string]bool) error {
+		if flagDungeon < 0 {
+			return fmt.Errorf("-dungeon-max can't be negative")
+		}
+		if set["forward-fill"] &&
+			(set["bias"] || set["dungeon-max"] || set["early-weapon"]) {
+			return fmt.Errorf("-bias, -dungeon-max, and -early-weapon " +
+				"don't work with -forward-fill")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if flagGale != "progression" && flagGale != "convenience" {
+			return fmt.Errorf("-gale-warp must be progression or convenience")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		if flagWorkers < 1 {
+			return fmt.Errorf("-workers must be at least 1")
+		}
+		return nil
+	},
+	func(set map[string]bool) error {
+		return randomizer.CheckPalette(flagPalette)
+	},
+}
+
+// checkOptions returns an error describing the first conflict between the
+// given command-line options, if any.
+func checkOptions() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for _, rule := range optionRules {
+		if err := rule(set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the names in the slice that are in the set, in order.
+func setFlags(set map[string]bool, names []string) []string {
+	found := make([]string, 0)
+	for _, name := range names {
+		if set[name] {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// returns true iff the slice contains the string.
+func containsString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the slice without any instances of the string.
+func removeString(a []string, s string) []string {
+	b := make([]string, 0, len(a))
+	for _, v := range a {
+		if v != s {
+			b = append(b, v)
+		}
+	}
+	return b
+}
+
+// formats flag names as a list like "-a, -b, and -c".
+func joinFlags(names []string) string {
+	dashed := make([]string, len(names))
+	for i, name := range names {
+		dashed[i] = "-" + name
+	}
+
+	switch len(dashed) {
+	case 1:
+		return dashed[0]
+	case 2:
+		return dashed[0] + " and " + dashed[1]
+	}
+	return strings.Join(dashed[:len(dashed)-1], ", ") + ", and " +
+		dashed[len(dashed)-1]
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// a preset is a set of randomization flag values, stored as a JSON object
+// keyed by flag name. flags that can be given more than once use arrays.
+type preset map[string]interface{}
+
+// presets that can be used by name instead of by filename.
+var builtinPresets = map[string]preset{
+	"standard-race": {
+		"logic":         "casual",
+		"compass-hints": true,
+		"treewarp":      true,
+	},
+	"hard-race": {
+		"logic":         "hard",
+		"compass-hints": true,
+		"nomaps":        true,
+		"treewarp":      true,
+	},
+}
+
+// flags that can't be stored in presets, since they'd make every seed the
+// same.
+var unpresetFlags = []string{"daily", "seed"}
+
+// presetFlags returns the names of the flags that presets can set.
+func presetFlags() []string {
+	names := make([]string, 0, len(randomizeFlags))
+	for _, name := range randomizeFlags {
+		if name != "preset" && !containsString(unpresetFlags, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// loadPreset returns the built-in preset with the given name, or else reads a
+// preset from the file with that name.
+func loadPreset(name string) (preset, error) {
+	if p, ok := builtinPresets[name]; ok {
+		return p, nil
+	}
+	if !strings.HasSuffix(name, ".json") {
+		return nil, fmt.Errorf("unknown preset %q; built-in presets are: %s",
+			name, strings.Join(presetNames(), ", "))
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var p preset
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return p, nil
+}
+
+// applyPreset sets the flags in the preset that weren't given on the command
+// line, so that the command line can override parts of a preset. the values
+// are set without marking the flags as given, so the option rules only see
+// the command line.
+func applyPreset(p preset) error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	allowed := presetFlags()
+	names := make([]string, 0, len(p))
+	for name := range p {
+		if !containsString(allowed, name) {
+			return fmt.Errorf("presets can't set -%s", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if set[name] || p[name] == nil {
+			continue
+		}
+		// -logic on the command line replaces the preset's tier, and -hard
+		// is the old way of giving one.
+		if name == "hard" && set["logic"] {
+			continue
+		}
+		values, ok := p[name].([]interface{})
+		if !ok {
+			values = []interface{}{p[name]}
+		}
+		for _, v := range values {
+			if err := flag.Lookup(name).Value.Set(fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("preset value for -%s: %v", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// savePreset writes the current values of the preset flags to a file,
+// leaving out flags that have their default values.
+func savePreset(filename string) error {
+	p := make(preset)
+	for _, name := range presetFlags() {
+		if f := flag.Lookup(name); f.Value.String() != f.DefValue {
+			p[name] = f.Value.(flag.Getter).Get()
+		}
+	}
+
+	b, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(b, '\n'), 0644)
+}
+
+// presetNames returns the names of the built-in presets in order.
+func presetNames() []string {
+	names := make([]string, 0, len(builtinPresets))
+	for name := range builtinPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listPresets prints the built-in presets and the flags they set.
+func listPresets() {
+	for _, name := range presetNames() {
+		p := builtinPresets[name]
+		flags := make([]string, 0, len(p))
+		for flagName, v := range p {
+			flags = append(flags, fmt.Sprintf("-%s %v", flagName, v))
+		}
+		sort.Strings(flags)
+		fmt.Printf("%s: %s\n", name, strings.Join(flags, " "))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jangler/oracles-randomizer/logic"
+)
+
+// parseArgs resets the flags and parses them from the given command line.
+// repeated flags keep their values when registered again, so those are
+// cleared first.
+func parseArgs(args ...string) {
+	flagJunk, flagKeep, flagStart, flagTrees = nil, nil, nil, nil
+	os.Args = append([]string{"oracles-randomizer"}, args...)
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	initFlags()
+}
+
+// checks that a saved preset only has the flags that were changed, and that
+// it can be loaded again without the option rules rejecting it.
+func TestSavePreset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.json")
+
+	parseArgs("-forward-fill", "-start-item", "sword 1", "-treewarp",
+		"-save-preset", filename)
+	if err := checkOptions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := savePreset(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"forward-fill": true,
+		"start-item":   []interface{}{"sword 1"},
+		"treewarp":     true,
+	}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("want %v, got %v", want, saved)
+	}
+
+	// these would each conflict with a flag that has its default value, if
+	// the preset set every flag.
+	for _, args := range [][]string{
+		{"-dry-run", "seasons"},
+		{"-export-graph", "ages", "-seed", "00000001"},
+		{},
+	} {
+		parseArgs(append(args, "-preset", filename)...)
+		p, err := loadPreset(flagPreset)
+		if err == nil {
+			err = applyPreset(p)
+		}
+		if err == nil {
+			err = checkOptions()
+		}
+		if err != nil {
+			t.Errorf("%v: %v", args, err)
+			continue
+		}
+		if !flagForward || !flagTreewarp ||
+			!reflect.DeepEqual([]string(flagStart), []string{"sword 1"}) {
+			t.Errorf("%v: preset values weren't applied", args)
+		}
+	}
+}
+
+// checks that -logic on the command line replaces a preset's -hard, instead
+// of conflicting with it.
+func TestPresetHard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "hard.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"hard": true}`),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parseArgs("-logic", "casual", "-preset", filename)
+	p, err := loadPreset(flagPreset)
+	if err == nil {
+		err = applyPreset(p)
+	}
+	if err == nil {
+		err = checkOptions()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tier, _ := logicTier(); tier != logic.TierCasual {
+		t.Errorf("want tier %v, got %v", logic.TierCasual, tier)
+	}
+
+	parseArgs("-hard", "-logic", "casual")
+	if err := checkOptions(); err == nil {
+		t.Errorf("-hard with -logic casual didn't conflict")
+	}
+}
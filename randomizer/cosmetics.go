@@ -0,0 +1,45 @@
+package randomizer
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// cosmetic choices use their own random stream, derived from the seed, so that
+// changing cosmetic options never changes item placement. this constant is
+// just xored with the seed to make the stream differ from the placement one.
+const cosmeticSeedSalt = 0x636f736d
+
+// newCosmeticSource returns the random source for cosmetic options.
+func newCosmeticSource(seed uint32) *rand.Rand {
+	return rand.New(rand.NewSource(int64(seed ^ cosmeticSeedSalt)))
+}
+
+// rollTunicColor returns the tunic color for a -palette value, which is either
+// the name of a color or "random".
+func rollTunicColor(src *rand.Rand, palette string) (int, error) {
+	if palette == "random" {
+		return src.Intn(len(rom.TunicColors)), nil
+	}
+
+	for i, name := range rom.TunicColors {
+		if palette == name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid palette %q; try %s, or random", palette,
+		strings.Join(rom.TunicColors, ", "))
+}
+
+// CheckPalette returns an error if the palette isn't a valid -palette value.
+func CheckPalette(palette string) error {
+	if palette == "random" {
+		return nil
+	}
+	_, err := rollTunicColor(nil, palette)
+	return err
+}
@@ -0,0 +1,127 @@
+package randomizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/graph"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// explainChecks returns, for each slot holding a progression item, a set of
+// progression items that makes the slot reachable. each set starts as every
+// progression item from earlier spheres, and items are removed from it while
+// the slot stays reachable, so the sets are minimal but not necessarily the
+// smallest possible. rupee costs aren't considered.
+func explainChecks(rs *rom.State, g graph.Graph,
+	checks map[*graph.Node]*graph.Node, spheres [][]*graph.Node,
+	hard bool) map[*graph.Node][]*graph.Node {
+	// detach all items from their slots, so that items are only reachable
+	// through a node that's always satisfied.
+	for slot, item := range checks {
+		item.RemoveParent(slot)
+	}
+	defer func() {
+		for slot, item := range checks {
+			item.AddParents(slot)
+		}
+	}()
+	have := graph.NewNode("have", graph.AndType, false, false, false)
+
+	// returns true iff the slot is reachable with only the given items.
+	reachable := func(slot *graph.Node, items []*graph.Node) bool {
+		for _, item := range items {
+			item.AddParents(have)
+		}
+		g.ClearMarks()
+		result := slot.GetMark(slot, hard) == graph.MarkTrue
+		for _, item := range items {
+			item.RemoveParent(have)
+		}
+		return result
+	}
+
+	explanations := make(map[*graph.Node][]*graph.Node)
+	candidates := make([]*graph.Node, 0)
+	for _, sphere := range spheres {
+		slots := make([]*graph.Node, 0)
+		for _, node := range sphere {
+			if item := checks[node]; item != nil &&
+				!itemIsJunk(rs, item.Name) {
+				slots = append(slots, node)
+			}
+		}
+
+		for _, slot := range slots {
+			required := append([]*graph.Node{}, candidates...)
+			if !reachable(slot, required) {
+				continue // only reachable with rupees from later spheres
+			}
+			for i := 0; i < len(required); {
+				without := append(append([]*graph.Node{}, required[:i]...),
+					required[i+1:]...)
+				if reachable(slot, without) {
+					required = without
+				} else {
+					i++
+				}
+			}
+			explanations[slot] = required
+		}
+
+		// items from this sphere can be used in later ones.
+		for _, slot := range slots {
+			if !graph.IsNodeInSlice(checks[slot], candidates) {
+				candidates = append(candidates, checks[slot])
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Name < candidates[j].Name
+		})
+	}
+
+	return explanations
+}
+
+// logExplanations prints the requirements for each progression check to the
+// summary channel, in sphere order.
+func logExplanations(summary chan string,
+	checks map[*graph.Node]*graph.Node, spheres [][]*graph.Node,
+	explanations map[*graph.Node][]*graph.Node) {
+	for _, sphere := range spheres {
+		slots := make([]*graph.Node, 0)
+		for _, node := range sphere {
+			if _, ok := explanations[node]; ok {
+				slots = append(slots, node)
+			}
+		}
+		sort.Slice(slots, func(i, j int) bool {
+			return slots[i].Name < slots[j].Name
+		})
+
+		for _, slot := range slots {
+			// progressive items can share a nice name, so count them.
+			names, counts := make([]string, 0), make(map[string]int)
+			for _, item := range explanations[slot] {
+				name := getNiceName(item.Name)
+				if counts[name] == 0 {
+					names = append(names, name)
+				}
+				counts[name]++
+			}
+			for i, name := range names {
+				if counts[name] > 1 {
+					names[i] = fmt.Sprintf("%s x%d", name, counts[name])
+				}
+			}
+			requirement := "nothing"
+			if len(names) > 0 {
+				requirement = strings.Join(names, ", ")
+			}
+			summary <- fmt.Sprintf("%-28s <- %s: needs %s",
+				getNiceName(slot.Name), getNiceName(checks[slot].Name),
+				requirement)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package randomizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/graph"
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// names for logic node types in exported JSON.
+var exportTypeNames = map[logic.Type]string{
+	logic.RootType:    "root",
+	logic.AndType:     "and",
+	logic.OrType:      "or",
+	logic.AndSlotType: "and slot",
+	logic.OrSlotType:  "or slot",
+	logic.AndStepType: "and step",
+	logic.OrStepType:  "or step",
+	logic.HardAndType: "hard and",
+	logic.HardOrType:  "hard or",
+}
+
+// an exported logic node. parents are either node names or nested nodes.
+type exportNode struct {
+	Type    string        `json:"type"`
+	Parents []interface{} `json:"parents"`
+}
+
+// a tracker package: the item slots, the items that can go in them, and the
+// logic that connects them.
+type trackerPack struct {
+	Game      string                 `json:"game"`
+	Logic     string                 `json:"logic"`
+	Locations []string               `json:"locations"`
+	Items     []string               `json:"items"`
+	Nodes     map[string]*exportNode `json:"nodes"`
+}
+
+// ExportTracker returns JSON for a tracker package built from the logic for
+// the given tier and tricks. "hard" nodes are exported as such, and are only
+// meant to be used at the hard tier and above.
+func ExportTracker(game int, tier logic.Tier,
+	tricks map[string]bool) ([]byte, error) {
+	rs := rom.NewState(game)
+	var prenodes map[string]*logic.Node
+	if game == rom.GameSeasons {
+		prenodes = logic.GetSeasons(tier, tricks)
+	} else {
+		prenodes = logic.GetAges(tier, tricks)
+	}
+
+	pack := &trackerPack{
+		Game:      GameName(game),
+		Logic:     tier.String(),
+		Locations: make([]string, 0),
+		Items:     make([]string, 0),
+		Nodes:     make(map[string]*exportNode, len(prenodes)),
+	}
+	for name, pn := range prenodes {
+		pack.Nodes[name] = exportLogicNode(pn)
+		switch pn.Type {
+		case logic.AndSlotType, logic.OrSlotType:
+			if _, ok := rs.ItemSlots[name]; ok {
+				pack.Locations = append(pack.Locations, name)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, slot := range rs.ItemSlots {
+		seen[slot.VanillaTreasureName()] = true
+	}
+	if game == rom.GameSeasons {
+		for name := range logic.SeasonsExtraItems() {
+			seen[name] = true
+		}
+	}
+	for name := range seen {
+		pack.Items = append(pack.Items, name)
+	}
+
+	sort.Strings(pack.Locations)
+	sort.Strings(pack.Items)
+	return json.MarshalIndent(pack, "", "\t")
+}
+
+// converts a logic node and its nested parents for export.
+func exportLogicNode(pn *logic.Node) *exportNode {
+	en := &exportNode{
+		Type:    exportTypeNames[pn.Type],
+		Parents: make([]interface{}, len(pn.Parents)),
+	}
+	for i, parent := range pn.Parents {
+		if nested, ok := parent.(*logic.Node); ok {
+			en.Parents[i] = exportLogicNode(nested)
+		} else {
+			en.Parents[i] = parent
+		}
+	}
+	return en
+}
+
+// GraphFormats are the formats that ExportGraph can write.
+var GraphFormats = []string{"dot", "graphml"}
+
+// colors for nodes in exported graphs, by sphere. they repeat after the last.
+var sphereColors = []string{"red", "orange", "gold", "green", "cyan",
+	"blue", "purple", "magenta"}
+
+// ExportGraph returns the logic graph for the given game and options as DOT
+// or GraphML. if placed is true, the graph also has the items placed in the
+// route for opts.Seed, and nodes are colored by the sphere they're reached
+// in, or gray if they can't be reached.
+func ExportGraph(ctx context.Context, game int, opts Options, format string,
+	placed bool) ([]byte, error) {
+	if game != rom.GameSeasons && game != rom.GameAges {
+		return nil, fmt.Errorf("unknown game %d", game)
+	}
+	if format != "dot" && format != "graphml" {
+		return nil, fmt.Errorf("invalid graph format %q; try %s", format,
+			strings.Join(GraphFormats, ", "))
+	}
+
+	rs := rom.NewState(game)
+	var g graph.Graph
+	var attrs graph.Attrs
+	if placed {
+		if opts.Log == nil {
+			opts.Log = func(string, ...interface{}) {}
+		}
+		if opts.Workers < 1 {
+			opts.Workers = 1
+		}
+		if err := opts.check(); err != nil {
+			return nil, err
+		}
+		ropts := opts.routeOptions()
+		if err := prepareRoute(rs, ropts); err != nil {
+			return nil, err
+		}
+		ri := findRoute(ctx, rs, opts.Seed, opts.Verbose, ropts, opts.Log)
+		if ri == nil {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("no route found")
+		}
+
+		g = ri.Route.Graph
+		attrs = sphereAttrs(g, getChecks(ri), opts.Tier >= logic.TierHard)
+	} else {
+		g = NewRoute(rs, opts.routeOptions()).Graph
+	}
+
+	var b bytes.Buffer
+	var err error
+	if format == "dot" {
+		err = g.WriteDOT(&b, attrs)
+	} else {
+		err = g.WriteGraphML(&b, attrs)
+	}
+	return b.Bytes(), err
+}
+
+// sphereAttrs returns graph attributes that color nodes by sphere and name
+// the items in slots.
+func sphereAttrs(g graph.Graph, checks map[*graph.Node]*graph.Node,
+	hard bool) graph.Attrs {
+	sphereOf := make(map[*graph.Node]int)
+	for i, sphere := range getSpheres(g, checks, hard) {
+		for _, node := range sphere {
+			sphereOf[node] = i
+		}
+	}
+
+	return func(node *graph.Node) map[string]string {
+		m := map[string]string{"color": "gray"}
+		if i, ok := sphereOf[node]; ok {
+			m["color"] = sphereColors[i%len(sphereColors)]
+			m["sphere"] = strconv.Itoa(i)
+		}
+		if item := checks[node]; item != nil {
+			m["item"] = item.Name
+		}
+		return m
+	}
+}
@@ -0,0 +1,297 @@
+package randomizer
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jangler/oracles-randomizer/graph"
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+var updateGolden = flag.Bool("update", false,
+	"rewrite golden files in testdata instead of comparing against them")
+
+var fuzzSeeds = flag.Int("fuzz-seeds", 50,
+	"number of seeds for TestBeatable to generate per game and logic tier")
+
+// settings for golden placements. changing logic or placement on purpose
+// means running the tests with -update and checking the diff.
+var goldenCases = []struct {
+	name string
+	game int
+	seed uint32
+	opts routeOptions
+}{
+	{"seasons_casual", rom.GameSeasons, 1, routeOptions{}},
+	{"seasons_hard", rom.GameSeasons, 2, routeOptions{tier: logic.TierHard}},
+	{"seasons_options", rom.GameSeasons, 3, routeOptions{
+		vanillaPercent: 25,
+		startItems:     []string{"feather 1"},
+		dupSeeds:       true,
+		removeMaps:     true,
+	}},
+	{"seasons_moosh", rom.GameSeasons, 4, routeOptions{companion: 3}},
+	{"ages_casual", rom.GameAges, 1, routeOptions{}},
+	{"ages_hard", rom.GameAges, 2, routeOptions{tier: logic.TierHard}},
+	{"ages_bias", rom.GameAges, 5, routeOptions{
+		bias:       biasOverworld,
+		dungeonMax: 1,
+	}},
+	{"seasons_junk", rom.GameSeasons, 6, routeOptions{junkWeights: map[string]int{
+		"gasha seed":     0,
+		"piece of heart": 8,
+		"rupees, 50":     4,
+	}}},
+	{"seasons_forward", rom.GameSeasons, 1, routeOptions{forwardFill: true}},
+	{"ages_forward", rom.GameAges, 2, routeOptions{
+		tier:        logic.TierHard,
+		forwardFill: true,
+	}},
+	// parallel attempts should give the same route no matter which worker
+	// finishes first. run with -race to check that workers share nothing
+	// they write to.
+	{"seasons_workers", rom.GameSeasons, 7, routeOptions{workers: 4}},
+}
+
+// formatRoute returns the parts of a route that depend on the RNG, in the
+// order they were decided.
+func formatRoute(ri *RouteInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "seed: %08x\n", ri.Seed)
+	fmt.Fprintf(&b, "companion: %d\n", ri.Companion)
+	for _, area := range sortedKeys(ri.Seasons) {
+		fmt.Fprintf(&b, "%s: %d\n", area, ri.Seasons[area])
+	}
+	ei, es := ri.UsedItems.Front(), ri.UsedSlots.Front()
+	for ei != nil {
+		fmt.Fprintf(&b, "%s <- %s\n", es.Value.(*graph.Node).Name,
+			ei.Value.(*graph.Node).Name)
+		ei, es = ei.Next(), es.Next()
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestGolden(t *testing.T) {
+	logf := func(string, ...interface{}) {}
+	for _, gc := range goldenCases {
+		if gc.opts.workers == 0 {
+			gc.opts.workers = 1
+		}
+		ri := findRoute(context.Background(), rom.NewState(gc.game), gc.seed,
+			false, gc.opts, logf)
+		if ri == nil {
+			t.Errorf("%s: no route found", gc.name)
+			continue
+		}
+		got := formatRoute(ri)
+
+		filename := filepath.Join("testdata", gc.name+".golden")
+		if *updateGolden {
+			if err := ioutil.WriteFile(filename, []byte(got),
+				0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s: route differs from %s; "+
+				"run with -update if this is intended", gc.name, filename)
+		}
+	}
+}
+
+// generates many seeds and checks that each can be finished, according to the
+// same solver that's used for spoiler log spheres.
+func TestBeatable(t *testing.T) {
+	n := *fuzzSeeds
+	if testing.Short() {
+		n = 5
+	}
+
+	for _, game := range []int{rom.GameSeasons, rom.GameAges} {
+		rs := rom.NewState(game)
+		for _, tier := range []logic.Tier{logic.TierCasual, logic.TierHard} {
+			opts := routeOptions{tier: tier, workers: 1}
+			src := rand.New(rand.NewSource(int64(game)))
+			seeds := make(chan uint32)
+			go func() {
+				for i := 0; i < n; i++ {
+					seeds <- uint32(src.Int31())
+				}
+				close(seeds)
+			}()
+
+			var wg sync.WaitGroup
+			for i := 0; i < runtime.NumCPU(); i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for seed := range seeds {
+						checkBeatable(t, rs, seed, opts)
+					}
+				}()
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// checkBeatable returns the route for a seed, or nil if it can't be found or
+// can't be finished.
+func checkBeatable(t *testing.T, rs *rom.State, seed uint32,
+	opts routeOptions) *RouteInfo {
+	ri := findRoute(context.Background(), rs, seed, false, opts,
+		func(string, ...interface{}) {})
+	if ri == nil {
+		t.Errorf("%s %s %08x: no route found", GameName(rs.Game), opts.tier,
+			seed)
+		return nil
+	}
+
+	checks := getChecks(ri)
+	for _, sphere := range getSpheres(ri.Route.Graph, checks,
+		opts.tier >= logic.TierHard) {
+		for _, node := range sphere {
+			if node.Name == "done" {
+				return ri
+			}
+		}
+	}
+	t.Errorf("%s %s %08x: done isn't reachable", GameName(rs.Game),
+		opts.tier, seed)
+	return nil
+}
+
+// checks that the dungeon limit holds for placed progression, and that seeds
+// can still be finished under it.
+func TestDungeonMax(t *testing.T) {
+	for _, game := range []int{rom.GameSeasons, rom.GameAges} {
+		rs := rom.NewState(game)
+		for _, bias := range []int{biasDungeon, biasOverworld} {
+			opts := routeOptions{bias: bias, dungeonMax: 2, workers: 1}
+			for seed := uint32(0); seed < 3; seed++ {
+				ri := checkBeatable(t, rs, seed, opts)
+				if ri == nil {
+					continue
+				}
+
+				inDungeon := make(map[int]int)
+				for slot, item := range getChecks(ri) {
+					if d := dungeonIndex(slot); d >= 0 &&
+						!itemIsJunk(rs, item.Name) &&
+						!itemIsDungeonSpecific(item.Name) &&
+						logic.RupeeValues[item.Name] == 0 {
+						inDungeon[d]++
+					}
+				}
+				for d, n := range inDungeon {
+					if n > opts.dungeonMax {
+						t.Errorf("%s %s %08x: %d progression items in d%d",
+							GameName(game), biasNames[bias], seed, n, d)
+					}
+				}
+			}
+		}
+	}
+}
+
+// checks that an item with more than one copy in the pool can be given at the
+// start, and that it stays given while the other copies are placed.
+func TestDuplicateStartItem(t *testing.T) {
+	rs := rom.NewState(rom.GameSeasons)
+	for _, forward := range []bool{false, true} {
+		opts := routeOptions{startItems: []string{"bombs, 10"},
+			forwardFill: forward, workers: 1}
+		for seed := uint32(0); seed < 5; seed++ {
+			ri := checkBeatable(t, rs, seed, opts)
+			if ri == nil {
+				continue
+			}
+			g := ri.Route.Graph
+			if !firstSphere(g, getChecks(ri), false)[g["bombs, 10"]] {
+				t.Errorf("%08x: bombs not given", seed)
+			}
+		}
+	}
+}
+
+// checks that a weapon is placed in the first sphere when asked for.
+func TestEarlyWeapon(t *testing.T) {
+	for _, game := range []int{rom.GameSeasons, rom.GameAges} {
+		rs := rom.NewState(game)
+		opts := routeOptions{earlyWeapon: true, workers: 1}
+		for seed := uint32(0); seed < 5; seed++ {
+			ri := checkBeatable(t, rs, seed, opts)
+			if ri == nil {
+				continue
+			}
+
+			checks, found := getChecks(ri), false
+			spheres := getSpheres(ri.Route.Graph, checks, false)
+			for _, node := range spheres[0] {
+				if item := checks[node]; item != nil &&
+					earlyWeaponNames[item.Name] {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s %08x: no weapon in first sphere",
+					GameName(game), seed)
+			}
+		}
+	}
+
+	// with every weapon given at the start, there's none left to place, but
+	// the player still has one.
+	rs := rom.NewState(rom.GameAges)
+	opts := routeOptions{earlyWeapon: true, workers: 1,
+		startItems: []string{"sword 1", "bombs, 10"}}
+	for seed := uint32(0); seed < 3; seed++ {
+		checkBeatable(t, rs, seed, opts)
+	}
+}
+
+// checks that every slot can be reached unless only beating the game is asked
+// for.
+func TestAccess(t *testing.T) {
+	for _, game := range []int{rom.GameSeasons, rom.GameAges} {
+		rs := rom.NewState(game)
+		for access := range accessNames {
+			opts := routeOptions{access: access, workers: 1}
+			for seed := uint32(0); seed < 20; seed++ {
+				ri := checkBeatable(t, rs, seed, opts)
+				if ri == nil || access == accessBeatable {
+					continue
+				}
+				if slot := unreachableSlot(ri.Route.Graph, ri,
+					false); slot != nil {
+					t.Errorf("%s %08x: %s isn't reachable", GameName(game),
+						seed, slot.Name)
+				}
+			}
+		}
+	}
+}
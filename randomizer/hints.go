@@ -0,0 +1,45 @@
+package randomizer
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/graph"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// hints use their own random stream for the same reason cosmetics do: turning
+// hints on or off shouldn't change item placement.
+const hintSeedSalt = 0x68696e74
+
+// newHintSource returns the random source for hint choices.
+func newHintSource(seed uint32) *rand.Rand {
+	return rand.New(rand.NewSource(int64(seed ^ hintSeedSalt)))
+}
+
+// chooseMapHints returns up to n random slots holding progression items, for
+// the treasure map to mark. jewels and seed trees aren't chosen, since the map
+// marks jewels anyway and trees aren't worth a hint.
+func chooseMapHints(rs *rom.State, src *rand.Rand,
+	checks map[*graph.Node]*graph.Node, n int) []*graph.Node {
+	slots := make([]*graph.Node, 0)
+	for slot, item := range checks {
+		if isCompassHintItem(rs, item.Name) && !slotIsSeedTree(slot.Name) &&
+			!strings.HasSuffix(item.Name, " jewel") {
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Name < slots[j].Name
+	})
+	src.Shuffle(len(slots), func(i, j int) {
+		slots[i], slots[j] = slots[j], slots[i]
+	})
+
+	if len(slots) > n {
+		slots = slots[:n]
+	}
+	return slots
+}
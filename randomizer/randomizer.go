@@ -0,0 +1,477 @@
+// Package randomizer generates randomized seeds from vanilla US oracles ROMs.
+// It's used by the command-line program, and can also be embedded in other
+// programs through Generate.
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/graph"
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+type logFunc func(string, ...interface{})
+
+// GameName returns the short name associated with a game number.
+func GameName(game int) string {
+	switch game {
+	case rom.GameAges:
+		return "ooa"
+	case rom.GameSeasons:
+		return "oos"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Options are the settings for generating a seed. the zero value gives a
+// casual seed with default settings; only Seed needs to be set. Licensee
+// must be negative to keep the vanilla licensee code.
+type Options struct {
+	Seed           uint32            // same seed and options give same ROM
+	Tier           logic.Tier        // logic difficulty
+	Tricks         map[string]bool   // override tier for named tricks
+	VanillaPercent int               // chance for each slot to keep its item
+	StartItems     []string          // treasures given at start
+	DupSeeds       bool              // extra trees can duplicate seed types
+	FixedTrees     map[string]string // seed types for specific trees
+	RemoveMaps     bool              // remove dungeon maps and compasses
+	MapHints       int               // treasure map sparkles, seasons only
+	CompassHints   bool              // compass beeps for progression items
+	StartingHearts int               // 0 means rom.VanillaHearts
+	KeepCutscenes  []string          // skips to turn off; see rom.SkipNames
+	Companion      int               // 1-3 for ricky, dimitri, moosh; 0 rolls
+	ForwardFill    bool              // use the old placement algorithm
+	Bias           int               // see ParseBias; needs assumed fill
+	DungeonMax     int               // progression per dungeon; 0 for any
+	JunkWeights    map[string]int    // relative odds of filler items
+	EarlyWeapon    bool              // weapon in the first sphere
+	Access         int               // see ParseAccess
+	NoMusic        bool
+	Treewarp       bool
+	Palette        string // tunic color name; "" or "random" rolls one
+	Title          string // cartridge header title; "" keeps vanilla
+	Licensee       int    // old licensee code; negative keeps vanilla
+	Workers        int    // placement attempts to run at once
+
+	// Verbose and Log control progress messages. Log acts like fmt.Printf
+	// with an added newline, and may be nil.
+	Verbose bool
+	Log     func(string, ...interface{}) `json:"-"`
+}
+
+// routeOptions converts opts to the options used by route finding.
+func (opts Options) routeOptions() routeOptions {
+	return routeOptions{
+		vanillaPercent: opts.VanillaPercent,
+		startItems:     opts.StartItems,
+		tier:           opts.Tier,
+		tricks:         opts.Tricks,
+		dupSeeds:       opts.DupSeeds,
+		fixedTrees:     opts.FixedTrees,
+		removeMaps:     opts.RemoveMaps,
+		mapHints:       opts.MapHints,
+		workers:        opts.Workers,
+		companion:      opts.Companion,
+		forwardFill:    opts.ForwardFill,
+		bias:           opts.Bias,
+		dungeonMax:     opts.DungeonMax,
+		junkWeights:    opts.JunkWeights,
+		earlyWeapon:    opts.EarlyWeapon,
+		access:         opts.Access,
+	}
+}
+
+// settingLines returns descriptions of the options that affect gameplay, in
+// the form "name: value", leaving out defaults. these are written to the log
+// file and the ROM.
+func (opts Options) settingLines() []string {
+	lines := []string{fmt.Sprintf("logic: %s", opts.Tier)}
+	for _, name := range logic.TrickNames() {
+		if enabled, ok := opts.Tricks[name]; ok {
+			lines = append(lines, fmt.Sprintf("trick %s: %v", name, enabled))
+		}
+	}
+	if opts.VanillaPercent > 0 {
+		lines = append(lines,
+			fmt.Sprintf("vanilla placement: %d%%", opts.VanillaPercent))
+	}
+	if len(opts.StartItems) > 0 {
+		lines = append(lines, fmt.Sprintf("starting items: %s",
+			strings.Join(opts.StartItems, "; ")))
+	}
+	if opts.StartingHearts > rom.VanillaHearts {
+		lines = append(lines,
+			fmt.Sprintf("starting hearts: %d", opts.StartingHearts))
+	}
+	if opts.Companion != 0 {
+		lines = append(lines,
+			fmt.Sprintf("companion: %s", companionNames[opts.Companion]))
+	}
+	if opts.ForwardFill {
+		lines = append(lines, "placement: forward fill")
+	}
+	if opts.Bias != 0 {
+		lines = append(lines,
+			fmt.Sprintf("placement bias: %s", biasNames[opts.Bias]))
+	}
+	if opts.DungeonMax > 0 {
+		lines = append(lines,
+			fmt.Sprintf("progression per dungeon: %d", opts.DungeonMax))
+	}
+	if opts.EarlyWeapon {
+		lines = append(lines, "early weapon: true")
+	}
+	if opts.Access != 0 {
+		lines = append(lines,
+			fmt.Sprintf("accessibility: %s", accessNames[opts.Access]))
+	}
+	if opts.DupSeeds {
+		lines = append(lines, "duplicate seed types: true")
+	}
+	if opts.RemoveMaps {
+		lines = append(lines, "maps and compasses: removed")
+	}
+	if opts.CompassHints {
+		lines = append(lines, "compass hints: true")
+	}
+	if opts.MapHints > 0 {
+		lines = append(lines, fmt.Sprintf("map hints: %d", opts.MapHints))
+	}
+	if opts.Treewarp {
+		lines = append(lines, "tree warp: true")
+	}
+	if len(opts.KeepCutscenes) > 0 {
+		lines = append(lines, fmt.Sprintf("vanilla cutscenes: %s",
+			strings.Join(opts.KeepCutscenes, "; ")))
+	}
+	trees := make([]string, 0, len(opts.FixedTrees))
+	for tree := range opts.FixedTrees {
+		trees = append(trees, tree)
+	}
+	sort.Strings(trees)
+	for _, tree := range trees {
+		lines = append(lines,
+			fmt.Sprintf("fixed %s: %s", tree, opts.FixedTrees[tree]))
+	}
+	junk := make([]string, 0, len(opts.JunkWeights))
+	for name := range opts.JunkWeights {
+		junk = append(junk, name)
+	}
+	sort.Strings(junk)
+	for _, name := range junk {
+		lines = append(lines,
+			fmt.Sprintf("junk weight %s: %d", name, opts.JunkWeights[name]))
+	}
+	return lines
+}
+
+// A Result is a generated seed.
+type Result struct {
+	ROM     []byte // randomized ROM data
+	Seed    uint32 // seed of the route used, which may differ from the option
+	Sum     []byte // SHA-1 sum of ROM
+	Spoiler string // text of the log file, with CRLF line endings
+}
+
+// Generate randomizes a copy of the given vanilla US ROM data. it returns
+// ctx.Err() if ctx is done before a route is found.
+func Generate(ctx context.Context, b []byte, opts Options) (Result, error) {
+	if !rom.IsAges(b) && !rom.IsSeasons(b) {
+		return Result{}, fmt.Errorf("not an oracles ROM")
+	}
+	if !rom.IsUS(b) {
+		return Result{}, fmt.Errorf("JP ROM; only US is supported")
+	}
+	if !rom.IsVanilla(b) {
+		return Result{}, fmt.Errorf("unrecognized oracles ROM")
+	}
+	game := rom.GameAges
+	if rom.IsSeasons(b) {
+		game = rom.GameSeasons
+	}
+
+	romData := make([]byte, len(b))
+	copy(romData, b)
+	return generate(ctx, game, romData, opts)
+}
+
+// DryRun finds a route and writes the spoiler log for the given game without
+// using a ROM, so the result's ROM and Sum are nil. the route is the same as
+// Generate would use.
+func DryRun(ctx context.Context, game int, opts Options) (Result, error) {
+	if game != rom.GameSeasons && game != rom.GameAges {
+		return Result{}, fmt.Errorf("unknown game %d", game)
+	}
+	return generate(ctx, game, nil, opts)
+}
+
+// generate randomizes romData in place, or only makes the spoiler log if it's
+// nil.
+func generate(ctx context.Context, game int, romData []byte,
+	opts Options) (Result, error) {
+	if opts.Log == nil {
+		opts.Log = func(string, ...interface{}) {}
+	}
+	if opts.Palette == "" {
+		opts.Palette = "random"
+	}
+	if opts.StartingHearts == 0 {
+		opts.StartingHearts = rom.VanillaHearts
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if err := opts.check(); err != nil {
+		return Result{}, err
+	}
+
+	rs := rom.NewState(game)
+	rs.SetMusic(!opts.NoMusic)
+	rs.SetTreewarp(opts.Treewarp)
+	if opts.CompassHints {
+		rs.SetCompassHint(func(name string) bool {
+			return isCompassHintItem(rs, name)
+		})
+	}
+	if err := rs.SetStartingHearts(opts.StartingHearts); err != nil {
+		return Result{}, err
+	}
+	for _, name := range opts.KeepCutscenes {
+		if err := rs.KeepCutscene(name); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := rs.SetTitle(opts.Title); err != nil {
+		return Result{}, err
+	}
+	if opts.Licensee >= 0 {
+		rs.SetLicensee(opts.Licensee)
+	}
+
+	seed, sum, spoiler, err := randomize(ctx, romData, rs, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		ROM:     romData,
+		Seed:    seed,
+		Sum:     sum,
+		Spoiler: spoiler,
+	}, nil
+}
+
+// check returns an error if the placement options are out of range or don't
+// work together.
+func (opts Options) check() error {
+	if opts.Companion < 0 || opts.Companion >= len(companionNames) {
+		return fmt.Errorf("invalid companion %d", opts.Companion)
+	}
+	if opts.Bias < 0 || opts.Bias >= len(biasNames) {
+		return fmt.Errorf("invalid placement bias %d", opts.Bias)
+	}
+	if opts.Access < 0 || opts.Access >= len(accessNames) {
+		return fmt.Errorf("invalid accessibility %d", opts.Access)
+	}
+	if opts.DungeonMax < 0 {
+		return fmt.Errorf("invalid progression per dungeon %d",
+			opts.DungeonMax)
+	}
+	if opts.ForwardFill &&
+		(opts.Bias != 0 || opts.DungeonMax != 0 || opts.EarlyWeapon) {
+		return fmt.Errorf("placement bias, dungeon limit, and " +
+			"early weapon don't work with forward fill")
+	}
+	return nil
+}
+
+// messes up rom data in place, unless it's nil, returning the seed of the
+// route used, the checksum of the new data, and the text of the log file.
+func randomize(ctx context.Context, romData []byte, rs *rom.State,
+	options Options) (uint32, []byte, string, error) {
+	game, opts := rs.Game, options.routeOptions()
+	verbose, logf := options.Verbose, options.Log
+
+	// sanity check beforehand
+	if romData != nil {
+		if errs := rs.Verify(romData); errs != nil {
+			if verbose {
+				for _, err := range errs {
+					logf(err.Error())
+				}
+			}
+			return 0, nil, "", errs[0]
+		}
+	}
+
+	// search for route
+	if err := prepareRoute(rs, opts); err != nil {
+		return 0, nil, "", err
+	}
+	ri := findRoute(ctx, rs, options.Seed, verbose, opts, logf)
+	if ri == nil {
+		if err := ctx.Err(); err != nil {
+			return 0, nil, "", err
+		}
+		return 0, nil, "", fmt.Errorf("no route found")
+	}
+
+	// cosmetics don't use the placement RNG
+	tunicColor, err := rollTunicColor(newCosmeticSource(ri.Seed),
+		options.Palette)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	rs.SetTunicColor(tunicColor)
+
+	checks := getChecks(ri)
+	var mapHints []*graph.Node
+	if game == rom.GameSeasons && opts.mapHints > 0 {
+		mapHints = chooseMapHints(rs, newHintSource(ri.Seed), checks,
+			opts.mapHints)
+		names := make([]string, len(mapHints))
+		for i, slot := range mapHints {
+			names[i] = slot.Name
+		}
+		if err := rs.SetTreasureMapSlots(names); err != nil {
+			return 0, nil, "", err
+		}
+	}
+
+	setStateData(rs, ri, logf, verbose)
+	settings := options.settingLines()
+	rs.SetInfo(fmt.Sprintf("oracles randomizer %s\nseed: %08x\n%s", Version,
+		ri.Seed, strings.Join(settings, "\n")))
+	var checksum []byte
+	if romData != nil {
+		// do it! (but don't write anything)
+		if checksum, err = rs.Mutate(romData); err != nil {
+			return 0, nil, "", err
+		}
+	}
+
+	spoiler := new(strings.Builder)
+	summary, summaryDone := getSummaryChannel(spoiler)
+
+	// write info to summary file
+	summary <- fmt.Sprintf("seed: %08x", ri.Seed)
+	if checksum != nil {
+		summary <- fmt.Sprintf("sha-1 sum: %x", checksum)
+	}
+	for _, line := range settings {
+		summary <- line
+	}
+	for i, slot := range mapHints {
+		summary <- fmt.Sprintf("treasure map hint: %s <- %s (clears with "+
+			"the %s jewel)", getNiceName(slot.Name),
+			getNiceName(checks[slot].Name), rom.TreasureMapJewels[i])
+	}
+	summary <- ""
+	summary <- ""
+	spheres := getSpheres(ri.Route.Graph, checks,
+		opts.tier >= logic.TierHard)
+	summary <- "-- progression items --"
+	summary <- ""
+	logSpheres(summary, rs, checks, spheres,
+		func(name string) bool { return !itemIsJunk(rs, name) })
+	summary <- ""
+	summary <- "-- other items --"
+	summary <- ""
+	logSpheres(summary, rs, checks, spheres,
+		func(name string) bool { return itemIsJunk(rs, name) })
+	summary <- ""
+	summary <- "-- playthrough --"
+	summary <- ""
+	logPlaythrough(summary, rs, checks, spheres)
+	summary <- ""
+	summary <- "-- logic explanations --"
+	summary <- ""
+	logExplanations(summary, checks, spheres, explainChecks(rs,
+		ri.Route.Graph, checks, spheres, opts.tier >= logic.TierHard))
+	if game == rom.GameSeasons {
+		summary <- ""
+		summary <- "default seasons:"
+		summary <- ""
+		for name, area := range rs.Seasons {
+			summary <- fmt.Sprintf("%-15s <- %s",
+				name[:len(name)-7], seasonsByID[int(area.New[0])])
+		}
+		summary <- ""
+		summary <- fmt.Sprintf("natzu region <- %s", []string{
+			"", "natzu prairie", "natzu river", "natzu wasteland",
+		}[ri.Companion])
+	} else {
+		summary <- ""
+		summary <- fmt.Sprintf("animal companion <- %s", []string{
+			"", "ricky", "dimitri", "moosh",
+		}[ri.Companion])
+	}
+
+	close(summary)
+	<-summaryDone
+
+	return ri.Seed, checksum, spoiler.String(), nil
+}
+
+// prepareRoute checks the route options against the game, and gives the
+// starting items, before a route is searched for.
+func prepareRoute(rs *rom.State, opts routeOptions) error {
+	if opts.vanillaPercent < 0 || opts.vanillaPercent > 100 {
+		return fmt.Errorf("vanilla percent must be from 0 to 100")
+	}
+	if err := checkStartItems(rs, opts.startItems); err != nil {
+		return err
+	}
+	if err := rs.SetStartingItems(opts.startItems); err != nil {
+		return err
+	}
+	if err := checkFixedTrees(rs, opts.fixedTrees); err != nil {
+		return err
+	}
+	return checkJunkWeights(rs, opts.junkWeights)
+}
+
+// itemIsJunk returns true iff the item with the given name can never be
+// progression, regardless of context.
+func itemIsJunk(rs *rom.State, name string) bool {
+	switch rs.Treasures[name].ID() {
+	// heart refill, PoH, HC, ring, compass, dungeon map, gasha seed
+	case 0x29, 0x2a, 0x2b, 0x2d, 0x32, 0x33, 0x34:
+		return true
+	}
+	return false
+}
+
+// returns true iff the compass should beep for the treasure when compass hints
+// are on. dungeon-specific items are left out, since boss keys always beep and
+// small keys would make the hint useless.
+func isCompassHintItem(rs *rom.State, name string) bool {
+	return name != "" && !itemIsJunk(rs, name) && !itemIsDungeonSpecific(name)
+}
+
+// setStateData sets the ROM state's slots, seasons, and companion based on the
+// given route.
+func setStateData(rs *rom.State, ri *RouteInfo, logf logFunc, verbose bool) {
+	// place selected treasures in slots
+	checks := getChecks(ri)
+	for slot, item := range checks {
+		if verbose {
+			logf("%s <- %s", slot.Name, item.Name)
+		}
+		rs.ItemSlots[slot.Name].Treasure = rs.Treasures[item.Name]
+	}
+
+	// set season data
+	if rs.Game == rom.GameSeasons {
+		for area, id := range ri.Seasons {
+			rs.Seasons[fmt.Sprintf("%s season", area)].New = []byte{id}
+		}
+	}
+
+	rs.SetAnimal(ri.Companion)
+}
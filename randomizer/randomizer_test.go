@@ -0,0 +1,57 @@
+package randomizer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+func TestGenerateRejectsUnknownROM(t *testing.T) {
+	b := make([]byte, 0x100000)
+	if _, err := Generate(context.Background(), b, Options{}); err == nil {
+		t.Error("expected error for non-oracles ROM")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	res, err := DryRun(context.Background(), rom.GameSeasons,
+		Options{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ROM != nil || res.Sum != nil {
+		t.Error("dry run returned ROM data")
+	}
+	if !strings.Contains(res.Spoiler, "-- progression items --") {
+		t.Error("dry run spoiler has no progression items")
+	}
+}
+
+func TestExportGraph(t *testing.T) {
+	b, err := ExportGraph(context.Background(), rom.GameAges, Options{},
+		"dot", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.HasPrefix(s, "digraph {") ||
+		strings.Contains(s, "sphere=") {
+		t.Error("logic graph isn't DOT, or has spheres")
+	}
+
+	b, err = ExportGraph(context.Background(), rom.GameSeasons,
+		Options{Seed: 1}, "graphml", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.Contains(s, `<data key="sphere">0</data>`) ||
+		!strings.Contains(s, `<data key="item">`) {
+		t.Error("placed graph has no spheres or items")
+	}
+
+	if _, err := ExportGraph(context.Background(), rom.GameAges, Options{},
+		"png", false); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
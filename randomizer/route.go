@@ -0,0 +1,1531 @@
+package randomizer
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jangler/oracles-randomizer/graph"
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// give up completely if routing fails too many times
+const maxTries = 50
+
+// adds nodes to the map based on default contents of item slots.
+func addDefaultItemNodes(rs *rom.State, nodes map[string]*logic.Node) {
+	for key, slot := range rs.ItemSlots {
+		if key != "temple of seasons" { // real rod is an Or, not a Root
+			nodes[slot.VanillaTreasureName()] = logic.Root()
+		}
+	}
+}
+
+// A Route is a set of information needed for finding an item placement route.
+type Route struct {
+	Rom    *rom.State // only read during placement
+	Graph  graph.Graph
+	Slots  map[string]*graph.Node
+	Rupees int
+}
+
+// NewRoute returns an initialized route with the nodes allowed by the options'
+// logic settings, and those nodes with the names in the starting items
+// functioning as givens (always satisfied). If no names are given, only the
+// normal start node functions as a given.
+func NewRoute(rs *rom.State, opts routeOptions) *Route {
+	g := graph.New()
+
+	var totalPrenodes map[string]*logic.Node
+	if rs.Game == rom.GameSeasons {
+		totalPrenodes = logic.GetSeasons(opts.tier, opts.tricks)
+	} else {
+		totalPrenodes = logic.GetAges(opts.tier, opts.tricks)
+	}
+	addDefaultItemNodes(rs, totalPrenodes)
+
+	// make start nodes given. they're rooted in their own node rather than
+	// made parentless, since copies left in the pool still add their slots
+	// as parents, and rather than in "start", which assumed fill takes away
+	// from items as it places them.
+	if len(opts.startItems) > 0 {
+		totalPrenodes[startItemsNode] = logic.And()
+	}
+	for _, key := range opts.startItems {
+		totalPrenodes[key] = logic.Root(startItemsNode)
+	}
+
+	addNodes(totalPrenodes, g)
+	addNodeParents(totalPrenodes, g)
+
+	openSlots := make(map[string]*graph.Node, 0)
+	for name, pn := range totalPrenodes {
+		switch pn.Type {
+		case logic.AndSlotType, logic.OrSlotType:
+			openSlots[name] = g[name]
+		}
+	}
+
+	return &Route{
+		Rom:   rs,
+		Graph: g,
+		Slots: openSlots,
+	}
+}
+
+func (r *Route) AddParent(child, parent string) {
+	r.Graph[child].AddParents(r.Graph[parent])
+}
+
+func (r *Route) ClearParents(node string) {
+	r.Graph[node].ClearParents()
+}
+
+func addNodes(prenodes map[string]*logic.Node, g graph.Graph) {
+	for key, pn := range prenodes {
+		switch pn.Type {
+		case logic.AndType, logic.AndSlotType, logic.AndStepType,
+			logic.HardAndType:
+			isStep := pn.Type == logic.AndSlotType ||
+				pn.Type == logic.AndStepType
+			isSlot := pn.Type == logic.AndSlotType
+			isHard := pn.Type == logic.HardAndType
+
+			node := graph.NewNode(key, graph.AndType, isStep, isSlot, isHard)
+			g.AddNodes(node)
+		case logic.OrType, logic.OrSlotType, logic.OrStepType, logic.RootType,
+			logic.HardOrType:
+			isStep := pn.Type == logic.OrSlotType ||
+				pn.Type == logic.OrStepType
+			isSlot := pn.Type == logic.OrSlotType
+			nodeType := graph.OrType
+			if pn.Type == logic.RootType {
+				nodeType = graph.RootType
+			}
+			isHard := pn.Type == logic.HardOrType
+
+			node := graph.NewNode(key, nodeType, isStep, isSlot, isHard)
+			g.AddNodes(node)
+		default:
+			panic("unknown logic type for " + key)
+		}
+	}
+}
+
+func addNodeParents(prenodes map[string]*logic.Node, gs ...graph.Graph) {
+	for k, pn := range prenodes {
+		for _, g := range gs {
+			if g[k] == nil {
+				continue
+			}
+			for _, parent := range pn.Parents {
+				if g[parent.(string)] == nil {
+					continue
+				}
+				g.AddParents(map[string][]string{k: []string{parent.(string)}})
+			}
+		}
+	}
+}
+
+type RouteInfo struct {
+	Route                *Route
+	Seed                 uint32
+	Seasons              map[string]byte
+	Companion            int // 1 to 3
+	UsedItems, UsedSlots *list.List
+	AttemptCount         int
+}
+
+const (
+	ricky   = 1
+	dimitri = 2
+	moosh   = 3
+)
+
+// companionNames are indexed by companion number; 0 means random.
+var companionNames = []string{"random", "ricky", "dimitri", "moosh"}
+
+// ParseCompanion returns the companion number for a name from
+// companionNames.
+func ParseCompanion(name string) (int, error) {
+	for i, s := range companionNames {
+		if s == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid companion %q; try %s", name,
+		strings.Join(companionNames, ", "))
+}
+
+// biasNames are indexed by placement bias; 0 means no bias.
+var biasNames = []string{"none", "dungeon", "overworld"}
+
+const (
+	biasDungeon   = 1
+	biasOverworld = 2
+)
+
+// how many times as likely progression is to go in a slot favored by the
+// placement bias.
+const biasWeight = 4
+
+// ParseBias returns the placement bias for a name from biasNames.
+func ParseBias(name string) (int, error) {
+	for i, s := range biasNames {
+		if s == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid placement bias %q; try %s", name,
+		strings.Join(biasNames, ", "))
+}
+
+// accessNames are indexed by accessibility. 0 means every slot can be
+// reached; "beatable" only means the game can be finished, so items it can be
+// finished without can go in slots that can't be reached.
+var accessNames = []string{"all", "beatable"}
+
+const (
+	accessAll      = 0
+	accessBeatable = 1
+)
+
+// ParseAccess returns the accessibility for a name from accessNames.
+func ParseAccess(name string) (int, error) {
+	for i, s := range accessNames {
+		if s == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid accessibility %q; try %s", name,
+		strings.Join(accessNames, ", "))
+}
+
+// routeOptions are settings that affect item placement, and the hints that
+// depend on it.
+type routeOptions struct {
+	vanillaPercent int      // chance for each slot to keep its vanilla item
+	startItems     []string // given at start and removed from the pool
+	tier           logic.Tier
+	tricks         map[string]bool   // override tier for named tricks
+	dupSeeds       bool              // let every extra tree grow any seed type
+	fixedTrees     map[string]string // seed types for specific trees
+	removeMaps     bool              // replace maps and compasses with filler
+	mapHints       int               // treasure map sparkles for other items
+	workers        int               // number of attempts to make at once
+	companion      int               // forced animal companion, if nonzero
+	forwardFill    bool              // use the old placement algorithm
+	bias           int               // favor dungeon or overworld slots
+	dungeonMax     int               // progression per dungeon, if nonzero
+	junkWeights    map[string]int    // redraw filler from these weights
+	earlyWeapon    bool              // put a weapon in the first sphere
+	access         int               // see ParseAccess
+}
+
+// the item that replaces starting items in the pool.
+const startItemFiller = "rupees, 20"
+
+// the parent of starting items in the graph.
+const startItemsNode = "starting items"
+
+// checkStartItems returns an error if any of the named items can't be given at
+// the start of the game.
+func checkStartItems(rs *rom.State, names []string) error {
+	inPool := poolNames(rs)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		switch {
+		case seen[name]:
+			return fmt.Errorf("duplicate starting item: %s", name)
+		case !inPool[name] || itemIsDungeonSpecific(name) ||
+			strings.HasSuffix(name, "flute") || strings.HasSuffix(name, "seeds"):
+			return fmt.Errorf("invalid starting item: %s", name)
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
+
+// poolNames returns the set of item names in the game's vanilla pool.
+func poolNames(rs *rom.State) map[string]bool {
+	inPool := make(map[string]bool)
+	for _, slot := range rs.ItemSlots {
+		inPool[slot.VanillaTreasureName()] = true
+	}
+	if rs.Game == rom.GameSeasons {
+		for name := range logic.SeasonsExtraItems() {
+			inPool[name] = true
+		}
+	}
+	return inPool
+}
+
+// returns true iff the item can be traded for other filler by junk weights.
+func isFillerName(name string) bool {
+	return name == "gasha seed" || name == "piece of heart" ||
+		logic.RupeeValues[name] > 0
+}
+
+// checkJunkWeights returns an error if any of the weights is negative or isn't
+// for filler in the game's pool, or if the weights would remove every rupee.
+// rupees are what make shops reachable, so a pool without them can't route.
+func checkJunkWeights(rs *rom.State, weights map[string]int) error {
+	inPool := poolNames(rs)
+	for name, weight := range weights {
+		if !inPool[name] || !isFillerName(name) {
+			return fmt.Errorf("invalid junk item: %s", name)
+		}
+		if weight < 0 {
+			return fmt.Errorf("junk weight for %s can't be negative", name)
+		}
+	}
+
+	for name := range inPool {
+		if weight, ok := weights[name]; logic.RupeeValues[name] > 0 &&
+			(!ok || weight > 0) {
+			return nil
+		}
+	}
+	return fmt.Errorf("junk weights can't remove every rupee; " +
+		"shops need them")
+}
+
+// CheckJunkWeights returns an error if the junk weights can't be used for the
+// given game.
+func CheckJunkWeights(game int, weights map[string]int) error {
+	return checkJunkWeights(rom.NewState(game), weights)
+}
+
+// replace each filler item in the pool with a random one from a weighted
+// table. filler that the weights don't mention is weighted by the number of
+// times it appears in the pool, so that only the mentioned items change in
+// proportion. returns a problem if the weights leave nothing to draw.
+func reweighJunk(src *rand.Rand, r *Route, itemList *list.List,
+	weights map[string]int) []string {
+	counts := make(map[string]int)
+	for e := itemList.Front(); e != nil; e = e.Next() {
+		if name := e.Value.(*graph.Node).Name; isFillerName(name) {
+			counts[name]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(counts)+len(weights))
+	for name := range counts {
+		names = append(names, name)
+	}
+	for name := range weights {
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	table, total := make([]int, len(names)), 0
+	for i, name := range names {
+		weight, ok := weights[name]
+		if !ok {
+			weight = counts[name]
+		}
+		table[i] = weight
+		total += weight
+	}
+	if total == 0 {
+		return []string{"junk weights leave no filler to choose from"}
+	}
+
+	for e := itemList.Front(); e != nil; e = e.Next() {
+		if !isFillerName(e.Value.(*graph.Node).Name) {
+			continue
+		}
+		n := src.Intn(total)
+		for i, weight := range table {
+			if n < weight {
+				e.Value = r.Graph[names[i]]
+				break
+			}
+			n -= weight
+		}
+	}
+	return nil
+}
+
+// returns true iff the item can only be placed in its own dungeon.
+func itemIsDungeonSpecific(name string) bool {
+	switch name {
+	case "dungeon map", "compass", "small key", "boss key":
+		return true
+	}
+	return strings.HasSuffix(name, " boss key")
+}
+
+// replace one instance of each starting item in the pool with filler, so that
+// the numbers of items and slots still match.
+func removeStartItems(r *Route, itemList *list.List, names []string) {
+	for _, name := range names {
+		for e := itemList.Front(); e != nil; e = e.Next() {
+			if e.Value.(*graph.Node).Name == name {
+				e.Value = r.Graph[startItemFiller]
+				break
+			}
+		}
+	}
+}
+
+// returns an error if a fixed tree isn't a seed tree in the game or is given
+// something other than a type of seed.
+func checkFixedTrees(rs *rom.State, trees map[string]string) error {
+	for tree, seed := range trees {
+		if _, ok := rs.ItemSlots[tree]; !ok || !slotIsSeedTree(tree) {
+			return fmt.Errorf("invalid seed tree: %s", tree)
+		}
+		if !isSeedName(seed) {
+			return fmt.Errorf("invalid seed type for %s: %s", tree, seed)
+		}
+	}
+	return nil
+}
+
+// place the given seed types in their trees before anything else is slotted.
+// if the pool doesn't have a seed item of the right type left, another seed
+// item is changed to it, preferring types that the pool has more than one of,
+// so that every tree still gets exactly one seed item.
+func placeFixedTrees(r *Route, trees map[string]string,
+	itemList, usedItems, slotList, usedSlots *list.List) {
+	names := make([]string, 0, len(trees))
+	for tree := range trees {
+		names = append(names, tree)
+	}
+	sort.Strings(names)
+
+	for _, tree := range names {
+		seed := r.Graph[trees[tree]]
+		eItem := findSeedItem(itemList, func(node *graph.Node) bool {
+			return node == seed
+		})
+		if eItem == nil {
+			counts := make(map[*graph.Node]int)
+			for e := itemList.Front(); e != nil; e = e.Next() {
+				counts[e.Value.(*graph.Node)]++
+			}
+			eItem = findSeedItem(itemList, func(node *graph.Node) bool {
+				return counts[node] > 1
+			})
+			if eItem == nil {
+				eItem = findSeedItem(itemList, func(*graph.Node) bool {
+					return true
+				})
+			}
+			eItem.Value = seed
+		}
+
+		for e := slotList.Front(); e != nil; e = e.Next() {
+			slot := e.Value.(*graph.Node)
+			if slot.Name == tree {
+				seed.AddParents(slot)
+				usedItems.PushBack(itemList.Remove(eItem))
+				usedSlots.PushBack(slotList.Remove(e))
+				break
+			}
+		}
+	}
+}
+
+// returns the first seed item in the list that matches the predicate.
+func findSeedItem(itemList *list.List,
+	match func(*graph.Node) bool) *list.Element {
+	for e := itemList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*graph.Node)
+		if isSeedName(node.Name) && match(node) {
+			return e
+		}
+	}
+	return nil
+}
+
+// replace all dungeon maps and compasses in the pool with filler, so that
+// their chests can hold other items.
+func removeMapsAndCompasses(r *Route, itemList *list.List) {
+	for e := itemList.Front(); e != nil; e = e.Next() {
+		switch e.Value.(*graph.Node).Name {
+		case "dungeon map", "compass":
+			e.Value = r.Graph[startItemFiller]
+		}
+	}
+}
+
+// attempts to create a path to the given targets by placing different items in
+// slots. returns nils if no route is found. if opts.workers is more than one,
+// attempts are made in parallel; see findRouteParallel. the search stops
+// between attempts if ctx is done.
+func findRoute(ctx context.Context, rs *rom.State, seed uint32, verbose bool,
+	opts routeOptions, logf logFunc) *RouteInfo {
+	if opts.workers > 1 {
+		return findRouteParallel(ctx, rs, seed, verbose, opts, logf)
+	}
+
+	for tries := 0; tries < maxTries; tries++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+		logf("trying seed %08x", seed)
+		ri, next, problems := tryRoute(rs, seed, verbose, opts, logf)
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				logf("abort; %s", problem)
+			}
+			return nil
+		}
+		if ri != nil {
+			ri.AttemptCount = tries + 1
+			return ri
+		}
+		seed = next
+	}
+
+	logf("abort; could not find route after %d tries", maxTries)
+	return nil
+}
+
+// makes one attempt at a route with the given seed. if the attempt fails, the
+// seed for the next attempt is returned instead of a route. problems are
+// returned if the item and slot pools can't work with any seed. rs must not be
+// modified, since findRouteParallel shares it between workers.
+func tryRoute(rs *rom.State, seed uint32, verbose bool, opts routeOptions,
+	logf logFunc) (*RouteInfo, uint32, []string) {
+	// items placed where they can't be reached can lock away slots that
+	// later items need, so a "beatable" attempt that fails is made again as
+	// an "all" attempt. that way it never fails where "all" wouldn't, and
+	// the next seed is the same.
+	if opts.access == accessBeatable && !opts.forwardFill {
+		ri, _, problems := tryAccess(rs, seed, verbose, opts, logf)
+		if ri != nil || len(problems) > 0 {
+			return ri, 0, problems
+		}
+		if verbose {
+			logf("retrying seed %08x with every slot reachable", seed)
+		}
+		opts.access = accessAll
+	}
+	return tryAccess(rs, seed, verbose, opts, logf)
+}
+
+// tryAccess acts as tryRoute, placing items by opts.access alone.
+func tryAccess(rs *rom.State, seed uint32, verbose bool, opts routeOptions,
+	logf logFunc) (*RouteInfo, uint32, []string) {
+	game, hard := rs.Game, opts.tier >= logic.TierHard
+
+	// keep track of which items we've popped off the stacks. these lists are
+	// parallel; i.e. the first item is in the first slot
+	ri := &RouteInfo{
+		Seed:      seed,
+		UsedItems: list.New(),
+		UsedSlots: list.New(),
+	}
+	src := rand.New(rand.NewSource(int64(seed)))
+
+	r := NewRoute(rs, opts)
+	ri.Companion = rollAnimalCompanion(src, r, game, opts.companion)
+	itemList, slotList := initRouteInfo(src, r, game, ri.Companion,
+		opts.dupSeeds)
+	removeStartItems(r, itemList, opts.startItems)
+	placeFixedTrees(r, opts.fixedTrees,
+		itemList, ri.UsedItems, slotList, ri.UsedSlots)
+
+	// slot initial nodes before algorithm slots progression items
+	if game == rom.GameSeasons {
+		ri.Seasons = rollSeasons(src, r)
+	}
+	if opts.removeMaps {
+		removeMapsAndCompasses(r, itemList)
+	}
+	if len(opts.junkWeights) > 0 {
+		if problems := reweighJunk(src, r, itemList,
+			opts.junkWeights); len(problems) > 0 {
+			return nil, 0, problems
+		}
+	}
+	placeDungeonItems(src, r, game, !opts.removeMaps,
+		itemList, ri.UsedItems, slotList, ri.UsedSlots)
+	placeVanillaItems(src, r, opts.vanillaPercent, ri.Companion,
+		itemList, ri.UsedItems, slotList, ri.UsedSlots)
+
+	if problems := auditPools(itemList, slotList); len(problems) > 0 {
+		return nil, 0, problems
+	}
+
+	var placed bool
+	if opts.forwardFill {
+		placed = forwardFill(r, src, hard, verbose, logf,
+			itemList, ri.UsedItems, slotList, ri.UsedSlots)
+	} else {
+		placed = assumedFill(r, src, hard, verbose, logf, opts,
+			itemList, ri.UsedItems, slotList, ri.UsedSlots)
+	}
+	if placed && opts.access != accessBeatable {
+		if slot := unreachableSlot(r.Graph, ri, hard); slot != nil {
+			if verbose {
+				logf("%s isn't reachable in order", slot.Name)
+			}
+			placed = false
+		}
+	}
+	if placed {
+		ri.Route = r
+		return ri, 0, nil
+	}
+
+	// get a new seed for the next attempt
+	return nil, uint32(src.Int31()), nil
+}
+
+// forwardFill places progression items in slots that are already reachable,
+// backtracking when it runs out of options, until the game can be finished,
+// then fills the rest of the slots the same way. returns false if the
+// placement gets stuck.
+func forwardFill(r *Route, src *rand.Rand, hard, verbose bool, logf logFunc,
+	itemList, usedItems, slotList, usedSlots *list.List) bool {
+	slotRecord := 0
+	i, maxIterations := 0, 1+itemList.Len()
+
+	// slot progression items
+	done := r.Graph["done"]
+	success := true
+	for done.GetMark(done, hard) != graph.MarkTrue {
+		if verbose {
+			logf("searching; have %d more slots", slotList.Len())
+			logf("%d/%d iterations", i, maxIterations)
+		}
+
+		eItem, eSlot := trySlotRandomItem(r, src, itemList, slotList,
+			countSteps, usedSlots.Len(), hard, false)
+
+		if eItem != nil {
+			item := itemList.Remove(eItem).(*graph.Node)
+			usedItems.PushBack(item)
+			slot := slotList.Remove(eSlot).(*graph.Node)
+			usedSlots.PushBack(slot)
+			r.Rupees += logic.RupeeValues[item.Name]
+
+			if usedSlots.Len() > slotRecord {
+				slotRecord = usedSlots.Len()
+				i, maxIterations = 0, 1+itemList.Len()
+			}
+		} else {
+			item := usedItems.Remove(usedItems.Back()).(*graph.Node)
+			slot := usedSlots.Remove(usedSlots.Back()).(*graph.Node)
+			r.Rupees -= logic.RupeeValues[item.Name]
+			itemList.PushBack(item)
+			slotList.PushBack(slot)
+			item.RemoveParent(slot)
+		}
+
+		i++
+		if i > maxIterations {
+			success = false
+			if verbose {
+				logf("maximum iterations reached")
+			}
+			break
+		}
+	}
+
+	if success {
+		// fill unused slots
+		for slotList.Len() > 0 {
+			if verbose {
+				logf("done; filling %d more slots", slotList.Len())
+				logf("%d/%d iterations", i, maxIterations)
+			}
+
+			eItem, eSlot := trySlotRandomItem(r, src, itemList, slotList,
+				countSteps, usedSlots.Len(), hard, true)
+
+			if eItem != nil {
+				item := itemList.Remove(eItem).(*graph.Node)
+				usedItems.PushBack(item)
+				slot := slotList.Remove(eSlot).(*graph.Node)
+				usedSlots.PushBack(slot)
+				r.Rupees += logic.RupeeValues[item.Name]
+
+				if usedSlots.Len() > slotRecord {
+					slotRecord = usedSlots.Len()
+					i, maxIterations = 0, 1+itemList.Len()
+				}
+			} else {
+				item := usedItems.Remove(usedItems.Back()).(*graph.Node)
+				slot := usedSlots.Remove(usedSlots.Back()).(*graph.Node)
+				r.Rupees -= logic.RupeeValues[item.Name]
+				itemList.PushBack(item)
+				slotList.PushBack(slot)
+				item.RemoveParent(slot)
+			}
+
+			i++
+			if i > maxIterations {
+				if verbose {
+					logf("maximum iterations reached")
+				}
+				break
+			}
+		}
+	}
+
+	return success && slotList.Len() == 0
+}
+
+// slots that can only hold one item, and the item they hold.
+var dummySlotItems = map[string]string{
+	"shop, 20 rupees": "bombs, 10",
+	"shop, 30 rupees": "wooden shield",
+}
+
+// assumedFill gives the player every unplaced progression item at the start,
+// then takes them away one at a time, placing each in a random slot that's
+// still reachable without it. rupees and junk are placed last. this spreads
+// progression through the spheres more evenly than forwardFill, which tends
+// to put early items in early slots. returns false if an item has nowhere to
+// go, or if the seed can't be finished in order.
+func assumedFill(r *Route, src *rand.Rand, hard, verbose bool, logf logFunc,
+	opts routeOptions, itemList, usedItems, slotList, usedSlots *list.List) bool {
+	start := r.Graph["start"]
+
+	// the dummy shop slots can only hold their vanilla items, so those are
+	// placed before anything can take their place. they aren't linked in the
+	// graph until the end, since the graph doesn't know that they cost
+	// rupees, and other copies of their items would otherwise be treated as
+	// free.
+	dummies := make(map[*graph.Node]*graph.Node)
+	for e := slotList.Front(); e != nil; {
+		eSlot := e
+		e = e.Next()
+		name, ok := dummySlotItems[eSlot.Value.(*graph.Node).Name]
+		if !ok {
+			continue
+		}
+		for eItem := itemList.Front(); eItem != nil; eItem = eItem.Next() {
+			if item := eItem.Value.(*graph.Node); item.Name == name {
+				slot := slotList.Remove(eSlot).(*graph.Node)
+				dummies[slot] = item
+				usedItems.PushBack(itemList.Remove(eItem))
+				usedSlots.PushBack(slot)
+				r.Rupees += logic.RupeeValues[item.Name]
+				break
+			}
+		}
+	}
+
+	elems := make([]*list.Element, 0, itemList.Len())
+	for e := itemList.Front(); e != nil; e = e.Next() {
+		elems = append(elems, e)
+	}
+	var progression, rupees, junk []*list.Element
+	for _, i := range src.Perm(len(elems)) {
+		e := elems[i]
+		item := e.Value.(*graph.Node)
+		switch {
+		case logic.RupeeValues[item.Name] > 0:
+			rupees = append(rupees, e)
+			r.Rupees += logic.RupeeValues[item.Name]
+		case !itemIsJunk(r.Rom, item.Name):
+			progression = append(progression, e)
+			item.AddParents(start)
+		default:
+			junk = append(junk, e)
+		}
+	}
+
+	done := r.Graph["done"]
+	if !r.Graph.Reachable(hard)[done] {
+		if verbose {
+			logf("done isn't reachable even with every item")
+		}
+		return false
+	}
+
+	// items that only fit in a few slots go first, while those slots are
+	// still open. after that, items that can only be placed in the fewest
+	// slots go first, so that early slots are still open for them. levels of
+	// progressive items are counted together, since the last one placed can
+	// only go where neither is needed.
+	groups := make(map[string][]*graph.Node)
+	for _, e := range progression {
+		item := e.Value.(*graph.Node)
+		group := progressiveGroup(item.Name)
+		if !isSeedName(item.Name) &&
+			!graph.IsNodeInSlice(item, groups[group]) {
+			groups[group] = append(groups[group], item)
+		}
+	}
+	open := make(map[string]int, len(groups))
+	for group, items := range groups {
+		for _, item := range items {
+			item.RemoveParent(start)
+		}
+		reached := r.Graph.Reachable(hard)
+		for e := slotList.Front(); e != nil; e = e.Next() {
+			if reached[e.Value.(*graph.Node)] {
+				open[group]++
+			}
+		}
+		for _, item := range items {
+			item.AddParents(start)
+		}
+	}
+	sort.SliceStable(progression, func(i, j int) bool {
+		a := progression[i].Value.(*graph.Node).Name
+		b := progression[j].Value.(*graph.Node).Name
+		if isSeedName(a) || isSeedName(b) {
+			return isSeedName(a) && !isSeedName(b)
+		}
+		return open[progressiveGroup(a)] < open[progressiveGroup(b)]
+	})
+
+	// an early weapon goes last, since that's when the fewest slots are
+	// left open without it. the choice is by name, since seasons has several
+	// bombs in the pool, and only weapons that get the player somewhere on
+	// their own are chosen.
+	var weapon *list.Element
+	if opts.earlyWeapon {
+		weapons := make([]int, 0)
+		for i, e := range progression {
+			name := e.Value.(*graph.Node).Name
+			if earlyWeaponNames[name] &&
+				!weaponNamed(progression, weapons, name) &&
+				weaponOpensSlots(r.Graph, start, progression, e, slotList,
+					hard) {
+				weapons = append(weapons, i)
+			}
+		}
+		if len(weapons) > 0 {
+			i := weapons[src.Intn(len(weapons))]
+			weapon = progression[i]
+			progression = append(append(progression[:i:i],
+				progression[i+1:]...), weapon)
+		} else if !startsWithWeapon(opts.startItems) {
+			if verbose {
+				logf("no weapon in the pool opens any slots")
+			}
+			return false
+		}
+	}
+
+	checks := make(map[*graph.Node]*graph.Node)
+	ei, es := usedItems.Front(), usedSlots.Front()
+	for ei != nil {
+		if slot := es.Value.(*graph.Node); dummies[slot] == nil {
+			checks[slot] = ei.Value.(*graph.Node)
+		}
+		ei, es = ei.Next(), es.Next()
+	}
+
+	// rupees don't open up the graph, but shops can't be bought from without
+	// them. they're counted from the start like the rest of progression, then
+	// placed where the player can get them in order.
+	filled := make(map[*graph.Node]bool)
+	swaps := 0
+
+	// the placement bias and dungeon limit only apply to progression.
+	inDungeon := make(map[int]int)
+	isProgression := func(item *graph.Node) bool {
+		return !itemIsJunk(r.Rom, item.Name) &&
+			logic.RupeeValues[item.Name] == 0
+	}
+	count := func(item, slot *graph.Node, n int) {
+		if d := dungeonIndex(slot); d >= 0 && isProgression(item) {
+			inDungeon[d] += n
+		}
+	}
+	for i, pool := range [][]*list.Element{progression, rupees, junk} {
+		inOrder := i == 1
+		for len(pool) > 0 {
+			eItem := pool[0]
+			pool = pool[1:]
+			item := eItem.Value.(*graph.Node)
+			if graph.IsNodeInSlice(start, item.Parents()) {
+				item.RemoveParent(start)
+			}
+
+			var reached map[*graph.Node]bool
+			switch {
+			case eItem == weapon:
+				reached = firstSphere(r.Graph, checks, hard)
+			case inOrder:
+				reached = reachedInOrder(r.Graph, checks, hard)
+			default:
+				reached = r.Graph.Reachable(hard)
+			}
+
+			// if only beating the game counts, anything it can be beaten
+			// without can go where it'll never be found. that includes
+			// rupees, once the game can be beaten with the ones spent so far.
+			if opts.access == accessBeatable && eItem != weapon &&
+				reached[done] {
+				reached = make(map[*graph.Node]bool, slotList.Len())
+				for e := slotList.Front(); e != nil; e = e.Next() {
+					reached[e.Value.(*graph.Node)] = true
+				}
+			}
+			weight := func(slot *graph.Node) int {
+				return slotWeight(opts, item, slot, inDungeon,
+					isProgression(item))
+			}
+
+			// the early weapon may only have the d0 sword chest to go in, so
+			// it skips the reduced odds for items there.
+			fitSrc := src
+			if eItem == weapon {
+				fitSrc = nil
+			}
+			eSlot := randomOpenSlot(r, src, fitSrc, item, slotList, hard,
+				reached, weight)
+			if eSlot != nil {
+				slot := slotList.Remove(eSlot).(*graph.Node)
+				item.AddParents(slot)
+				usedItems.PushBack(itemList.Remove(eItem))
+				usedSlots.PushBack(slot)
+				checks[slot] = item
+				filled[slot] = eItem != weapon
+				count(item, slot, 1)
+				if !inOrder {
+					r.Rupees += logic.RupeeValues[item.Name]
+				}
+				continue
+			}
+
+			// if every open slot needs the item, it can take the place of
+			// an item placed earlier, which goes back in the pool.
+			var eUsed *list.Element
+			if i < 2 && swaps < len(progression) {
+				eUsed = randomFilledSlot(r, src, fitSrc, item, usedItems,
+					usedSlots, filled, hard, reached)
+			}
+			if eUsed == nil {
+				if verbose {
+					logf("no slot for %s; have %d more slots", item.Name,
+						slotList.Len())
+				}
+				return false
+			}
+			swaps++
+
+			slot := slotAt(usedItems, usedSlots, eUsed)
+			evicted := eUsed.Value.(*graph.Node)
+			evicted.RemoveParent(slot)
+			count(evicted, slot, -1)
+			count(item, slot, 1)
+			if !inOrder && !graph.IsNodeInSlice(start, evicted.Parents()) {
+				evicted.AddParents(start)
+			}
+			item.AddParents(slot)
+			eUsed.Value = itemList.Remove(eItem)
+			checks[slot] = item
+			filled[slot] = eItem != weapon
+			pool = append([]*list.Element{itemList.PushBack(evicted)}, pool...)
+		}
+	}
+
+	for slot, item := range dummies {
+		item.AddParents(slot)
+		checks[slot] = item
+	}
+
+	// the graph doesn't account for spending rupees in order, so check the
+	// placement with the same solver as the spoiler log.
+	if !reachedInOrder(r.Graph, checks, hard)[done] {
+		if verbose {
+			logf("done isn't reachable in order")
+		}
+		return false
+	}
+	return true
+}
+
+// items that count as weapons for routeOptions.earlyWeapon. any season gives
+// the rod in seasons. if none are left in the pool, there must be one among
+// the starting items.
+var earlyWeaponNames = map[string]bool{
+	"sword 1":   true,
+	"bombs, 10": true,
+	"winter":    true,
+	"spring":    true,
+	"summer":    true,
+	"autumn":    true,
+}
+
+// startsWithWeapon returns true if one of the starting items counts as an
+// early weapon.
+func startsWithWeapon(startItems []string) bool {
+	for _, name := range startItems {
+		if earlyWeaponNames[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// weaponNamed returns true if one of the indexed progression items has the
+// given name.
+func weaponNamed(progression []*list.Element, weapons []int,
+	name string) bool {
+	for _, i := range weapons {
+		if progression[i].Value.(*graph.Node).Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// weaponOpensSlots returns true if the weapon reaches an open slot that can't
+// be reached with no items. slots that cost rupees don't count, since none are
+// placed yet when the weapon is.
+func weaponOpensSlots(g graph.Graph, start *graph.Node,
+	progression []*list.Element, weapon *list.Element, slotList *list.List,
+	hard bool) bool {
+	items := make([]*graph.Node, 0, len(progression))
+	for _, e := range progression {
+		item := e.Value.(*graph.Node)
+		if graph.IsNodeInSlice(start, item.Parents()) {
+			item.RemoveParent(start)
+			items = append(items, item)
+		}
+	}
+	base := g.Reachable(hard)
+	item := weapon.Value.(*graph.Node)
+	item.AddParents(start)
+	reached := g.Reachable(hard)
+	item.RemoveParent(start)
+	for _, item := range items {
+		item.AddParents(start)
+	}
+
+	for e := slotList.Front(); e != nil; e = e.Next() {
+		slot := e.Value.(*graph.Node)
+		if reached[slot] && !base[slot] && logic.NodeValues[slot.Name] >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unreachableSlot returns a used slot that the spoiler log's solver can't
+// reach, or nil if it can reach all of them.
+func unreachableSlot(g graph.Graph, ri *RouteInfo, hard bool) *graph.Node {
+	reached := reachedInOrder(g, getChecks(ri), hard)
+	for e := ri.UsedSlots.Front(); e != nil; e = e.Next() {
+		if slot := e.Value.(*graph.Node); !reached[slot] {
+			return slot
+		}
+	}
+	return nil
+}
+
+// firstSphere returns the set of nodes that the spoiler log's solver can
+// reach with no items, given the items in checks.
+func firstSphere(g graph.Graph, checks map[*graph.Node]*graph.Node,
+	hard bool) map[*graph.Node]bool {
+	reached := make(map[*graph.Node]bool)
+	if spheres := getSpheres(g, checks, hard); len(spheres) > 0 {
+		for _, node := range spheres[0] {
+			reached[node] = true
+		}
+	}
+	return reached
+}
+
+// reachedInOrder returns the set of nodes that the spoiler log's solver can
+// reach, given the items in checks.
+func reachedInOrder(g graph.Graph, checks map[*graph.Node]*graph.Node,
+	hard bool) map[*graph.Node]bool {
+	reached := make(map[*graph.Node]bool)
+	for _, sphere := range getSpheres(g, checks, hard) {
+		for _, node := range sphere {
+			reached[node] = true
+		}
+	}
+	return reached
+}
+
+// progressiveGroup returns the name of an item without its level, if it has
+// one, e.g. "sword" for "sword 2".
+func progressiveGroup(name string) string {
+	return strings.TrimRight(name, " 0123456789")
+}
+
+// randomOpenSlot returns a random element of the slot list that is in the
+// reached set, affordable, and can hold the item, or nil if there isn't one.
+// slots are chosen in proportion to their weight. fitSrc is passed to
+// itemFitsInSlot.
+func randomOpenSlot(r *Route, src, fitSrc *rand.Rand, item *graph.Node,
+	slotList *list.List, hard bool, reached map[*graph.Node]bool,
+	weight func(*graph.Node) int) *list.Element {
+	open := make([]*list.Element, 0, slotList.Len())
+	for e := slotList.Front(); e != nil; e = e.Next() {
+		slot := e.Value.(*graph.Node)
+		if reached[slot] && itemFitsInSlot(item, slot, fitSrc) &&
+			canAffordSlotWith(r, slot, hard, func(node *graph.Node) bool {
+				return reached[node]
+			}) {
+			for n := weight(slot); n > 0; n-- {
+				open = append(open, e)
+			}
+		}
+	}
+
+	if len(open) == 0 {
+		return nil
+	}
+	return open[src.Intn(len(open))]
+}
+
+// slotWeight returns how likely an item is to be placed in a slot, relative to
+// other slots, or zero if the slot is off limits.
+func slotWeight(opts routeOptions, item, slot *graph.Node,
+	inDungeon map[int]int, progression bool) int {
+	if !progression {
+		return 1
+	}
+
+	d := dungeonIndex(slot)
+	if d >= 0 && opts.dungeonMax > 0 && inDungeon[d] >= opts.dungeonMax {
+		return 0
+	}
+	if (opts.bias == biasDungeon && d >= 0) ||
+		(opts.bias == biasOverworld && d < 0) {
+		return biasWeight
+	}
+	return 1
+}
+
+// randomFilledSlot returns the element in usedItems for a random reachable
+// slot that was filled by assumedFill and can hold the item instead, or nil if
+// there isn't one. rupees are never chosen, since they're placed last. fitSrc
+// is passed to itemFitsInSlot.
+func randomFilledSlot(r *Route, src, fitSrc *rand.Rand, item *graph.Node,
+	usedItems, usedSlots *list.List, filled map[*graph.Node]bool, hard bool,
+	reached map[*graph.Node]bool) *list.Element {
+	candidates := make([]*list.Element, 0)
+	ei, es := usedItems.Front(), usedSlots.Front()
+	for ei != nil {
+		slot := es.Value.(*graph.Node)
+		if filled[slot] && reached[slot] &&
+			logic.RupeeValues[ei.Value.(*graph.Node).Name] == 0 &&
+			itemFitsInSlot(item, slot, fitSrc) &&
+			canAffordSlotWith(r, slot, hard, func(node *graph.Node) bool {
+				return reached[node]
+			}) {
+			candidates = append(candidates, ei)
+		}
+		ei, es = ei.Next(), es.Next()
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[src.Intn(len(candidates))]
+}
+
+// slotAt returns the slot in usedSlots that's paired with the given element
+// of usedItems.
+func slotAt(usedItems, usedSlots *list.List, e *list.Element) *graph.Node {
+	ei, es := usedItems.Front(), usedSlots.Front()
+	for ei != e {
+		ei, es = ei.Next(), es.Next()
+	}
+	return es.Value.(*graph.Node)
+}
+
+// findRouteParallel acts as findRoute, but runs opts.workers chains of attempts
+// at once, the first starting at the given seed. attempts are made in rounds,
+// and the route from the lowest-numbered worker that succeeds in the earliest
+// round is used, so the result depends only on the seed and number of workers,
+// not on timing. placement only reads the rom state and logic globals, so
+// workers don't need their own copies.
+func findRouteParallel(ctx context.Context, rs *rom.State, seed uint32,
+	verbose bool, opts routeOptions, logf logFunc) *RouteInfo {
+	seeds := make([]uint32, opts.workers)
+	src := rand.New(rand.NewSource(int64(seed)))
+	for i := range seeds {
+		if i == 0 {
+			seeds[i] = seed
+		} else {
+			seeds[i] = uint32(src.Int31())
+		}
+	}
+
+	// workers don't log, since their messages would be interleaved.
+	dummyLogf := func(string, ...interface{}) {}
+	routes := make([]*RouteInfo, opts.workers)
+	problems := make([][]string, opts.workers)
+
+	for tries := 0; tries < maxTries; tries++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+		names := make([]string, len(seeds))
+		for i, seed := range seeds {
+			names[i] = fmt.Sprintf("%08x", seed)
+		}
+		logf("trying seeds %s", strings.Join(names, ", "))
+
+		var wg sync.WaitGroup
+		for i := range seeds {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				routes[i], seeds[i], problems[i] =
+					tryRoute(rs, seeds[i], verbose, opts, dummyLogf)
+			}(i)
+		}
+		wg.Wait()
+
+		if len(problems[0]) > 0 {
+			for _, problem := range problems[0] {
+				logf("abort; %s", problem)
+			}
+			return nil
+		}
+		for _, ri := range routes {
+			if ri != nil {
+				ri.AttemptCount = (tries + 1) * opts.workers
+				return ri
+			}
+		}
+	}
+
+	logf("abort; could not find route after %d tries",
+		maxTries*opts.workers)
+	return nil
+}
+
+var (
+	seasonsByID = []string{"spring", "summer", "autumn", "winter"}
+	seasonAreas = []string{
+		"north horon", "eastern suburbs", "woods of winter", "spool swamp",
+		"holodrum plain", "sunken city", "lost woods", "tarm ruins",
+		"western coast", "temple remains",
+	}
+)
+
+// set the default seasons for all the applicable areas in the game, and return
+// a mapping of area name to season value.
+func rollSeasons(src *rand.Rand, r *Route) map[string]byte {
+	seasonMap := make(map[string]byte, len(seasonAreas))
+
+	for _, area := range seasonAreas {
+		// reset default seasons
+		for _, season := range seasonsByID {
+			r.ClearParents(fmt.Sprintf("%s default %s", area, season))
+		}
+
+		// roll new default season
+		id := src.Intn(len(seasonsByID))
+		season := seasonsByID[id]
+		r.AddParent(fmt.Sprintf("%s default %s", area, season), "start")
+		seasonMap[area] = byte(id)
+	}
+
+	return seasonMap
+}
+
+// randomly determines animal companion, unless forced is nonzero, and returns
+// its ID (1 to 3)
+func rollAnimalCompanion(src *rand.Rand, r *Route, game,
+	forced int) int {
+	// always roll, so that forcing the companion doesn't change the rest of
+	// the placement.
+	companion := src.Intn(3) + 1
+	if forced != 0 {
+		companion = forced
+	}
+
+	if game == rom.GameSeasons {
+		r.ClearParents("natzu prairie")
+		r.ClearParents("natzu river")
+		r.ClearParents("natzu wasteland")
+
+		switch companion {
+		case ricky:
+			r.AddParent("natzu prairie", "start")
+		case dimitri:
+			r.AddParent("natzu river", "start")
+		case moosh:
+			r.AddParent("natzu wasteland", "start")
+		}
+	} else {
+		r.ClearParents("ricky nuun")
+		r.ClearParents("dimitri nuun")
+		r.ClearParents("moosh nuun")
+
+		switch companion {
+		case ricky:
+			r.AddParent("ricky nuun", "start")
+		case dimitri:
+			r.AddParent("dimitri nuun", "start")
+		case moosh:
+			r.AddParent("moosh nuun", "start")
+		}
+	}
+
+	return companion
+}
+
+// dungeonIndex returns the index of a slot's dungeon if it's in a dungeon, or
+// -1 if it's not.
+func dungeonIndex(node *graph.Node) int {
+	isInDungeon, _ := regexp.MatchString(`^d\d `, node.Name)
+	if isInDungeon {
+		index, _ := strconv.Atoi(string(node.Name[1]))
+		return index
+	}
+	return -1
+}
+
+// place maps, compasses, and boss keys in chests in dungeons (before
+// attempting to slot the other ones).
+func placeDungeonItems(src *rand.Rand, r *Route, game int, maps bool,
+	itemList, usedItems, slotList, usedSlots *list.List) {
+
+	// place boss keys first
+	for i := 1; i < 9; i++ {
+		slotted := false
+		for ei := itemList.Front(); ei != nil && !slotted; ei = ei.Next() {
+			item := ei.Value.(*graph.Node)
+			if item.Name == fmt.Sprintf("d%d boss key", i) {
+				for es := slotList.Front(); es != nil; es = es.Next() {
+					slot := es.Value.(*graph.Node)
+					if dungeonIndex(slot) == i {
+						item.AddParents(slot)
+
+						usedSlots.PushBack(slot)
+						slotList.Remove(es)
+						usedItems.PushBack(item)
+						itemList.Remove(ei)
+
+						slotted = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	if !maps {
+		return
+	}
+
+	prefixes := []string{"d1", "d2", "d3", "d4", "d5"}
+	if game == rom.GameSeasons {
+		prefixes = append(prefixes, "d6")
+	} else {
+		prefixes = append(prefixes, "d6 present", "d6 past")
+	}
+	prefixes = append(prefixes, "d7", "d8")
+
+	// then place maps and compasses
+	for _, prefix := range prefixes {
+		for _, itemName := range []string{"dungeon map", "compass"} {
+			slotElem, itemElem, slotNode, itemNode :=
+				getDungeonItem(prefix, itemName, slotList, itemList)
+
+			usedSlots.PushBack(slotNode)
+			slotList.Remove(slotElem)
+			usedItems.PushBack(itemNode)
+			itemList.Remove(itemElem)
+
+			itemNode.AddParents(slotNode)
+		}
+	}
+}
+
+// place items in their vanilla slots, with the given percent chance for each
+// remaining slot.
+func placeVanillaItems(src *rand.Rand, r *Route, percent, companion int,
+	itemList, usedItems, slotList, usedSlots *list.List) {
+	if percent <= 0 {
+		return // don't consume any random numbers
+	}
+
+	for es := slotList.Front(); es != nil; {
+		next := es.Next()
+		slot := es.Value.(*graph.Node)
+
+		if src.Intn(100) < percent {
+			name := identifyFlute(
+				r.Rom.ItemSlots[slot.Name].VanillaTreasureName(), companion)
+			for ei := itemList.Front(); ei != nil; ei = ei.Next() {
+				item := ei.Value.(*graph.Node)
+				if item.Name == name && itemFitsInSlot(item, slot, nil) {
+					item.AddParents(slot)
+
+					usedSlots.PushBack(slot)
+					slotList.Remove(es)
+					usedItems.PushBack(item)
+					itemList.Remove(ei)
+
+					break
+				}
+			}
+		}
+
+		es = next
+	}
+}
+
+func getDungeonItem(prefix, itemName string, slotList,
+	itemList *list.List) (slotElem, itemElem *list.Element, slotNode, itemNode *graph.Node) {
+	for es := slotList.Front(); es != nil; es = es.Next() {
+		slot := es.Value.(*graph.Node)
+		if !strings.HasPrefix(slot.Name, prefix) {
+			continue
+		}
+
+		for ei := itemList.Front(); ei != nil; ei = ei.Next() {
+			item := ei.Value.(*graph.Node)
+			if item.Name != itemName {
+				continue
+			}
+
+			return es, ei, slot, item
+		}
+	}
+
+	panic("could not place dungeon-specific items")
+}
+
+func emptyList(l *list.List) []*graph.Node {
+	a := make([]*graph.Node, l.Len())
+	i := 0
+	for l.Len() > 0 {
+		a[i] = l.Remove(l.Front()).(*graph.Node)
+		i++
+	}
+	return a
+}
+
+func fillList(l *list.List, a []*graph.Node) {
+	for _, node := range a {
+		l.PushBack(node)
+	}
+}
+
+var seedNames = []string{"ember tree seeds", "scent tree seeds",
+	"pegasus tree seeds", "gale tree seeds", "mystery tree seeds"}
+
+// returns true iff the item is one of the types of tree seeds.
+func isSeedName(name string) bool {
+	for _, seed := range seedNames {
+		if name == seed {
+			return true
+		}
+	}
+	return false
+}
+
+// substitutes the identified flute for the given companion if the treasure is
+// the strange flute, and returns the name unchanged otherwise.
+func identifyFlute(treasureName string, companion int) string {
+	if treasureName == "strange flute" {
+		switch companion {
+		case ricky:
+			return "ricky's flute"
+		case dimitri:
+			return "dimitri's flute"
+		case moosh:
+			return "moosh's flute"
+		}
+	}
+	return treasureName
+}
+
+// return shuffled lists of item and slot nodes
+func initRouteInfo(src *rand.Rand, r *Route, game, companion int,
+	dupSeeds bool) (itemList, slotList *list.List) {
+	// get slices of names
+	var itemNames []string
+	if game == rom.GameSeasons {
+		itemNames = make([]string, 0,
+			len(r.Rom.ItemSlots)+len(logic.SeasonsExtraItems()))
+	} else {
+		itemNames = make([]string, 0, len(r.Rom.ItemSlots))
+	}
+	slotNames := make([]string, 0, len(r.Slots))
+	thisSeedNames := make([]string, len(seedNames))
+	copy(thisSeedNames, seedNames)
+	for key, slot := range r.Rom.ItemSlots {
+		switch key {
+		case "temple of seasons": // don't slot vanilla, seasonless rod
+			break
+		case "tarm ruins seed tree", "ambi's palace tree",
+			"rolling ridge east tree", "zora village tree":
+			// use random duplicate seed types, but only duplicate a seed type
+			// once unless more duplicates are allowed
+			index := src.Intn(len(thisSeedNames))
+			treasureName := thisSeedNames[index]
+			itemNames = append(itemNames, treasureName)
+			if !dupSeeds {
+				thisSeedNames = append(thisSeedNames[:index],
+					thisSeedNames[index+1:]...)
+			}
+		default:
+			treasureName := identifyFlute(
+				slot.VanillaTreasureName(), companion)
+			itemNames = append(itemNames, treasureName)
+		}
+	}
+	if game == rom.GameSeasons {
+		for key := range logic.SeasonsExtraItems() {
+			itemNames = append(itemNames, key)
+		}
+	}
+	for key := range r.Slots {
+		slotNames = append(slotNames, key)
+	}
+
+	// sort the slices so that order isn't dependent on map implementation,
+	// then shuffle the sorted slices
+	sort.Strings(itemNames)
+	sort.Strings(slotNames)
+	src.Shuffle(len(itemNames), func(i, j int) {
+		itemNames[i], itemNames[j] = itemNames[j], itemNames[i]
+	})
+	src.Shuffle(len(slotNames), func(i, j int) {
+		slotNames[i], slotNames[j] = slotNames[j], slotNames[i]
+	})
+
+	// push the graph nodes by name onto stacks
+	itemList = list.New()
+	slotList = list.New()
+	for _, key := range itemNames {
+		itemList.PushBack(r.Graph[key])
+	}
+	for _, key := range slotNames {
+		slotList.PushBack(r.Graph[key])
+	}
+
+	return itemList, slotList
+}
+
+// return the number of "step" nodes in the given set
+func countSteps(r *Route, hard bool) int {
+	r.Graph.ClearMarks()
+	reached := r.Graph.ExploreFromStart(hard)
+	count := 0
+	for node := range reached {
+		if node.IsStep && canAffordSlot(r, node, hard) {
+			count++
+		}
+	}
+	return count
+}
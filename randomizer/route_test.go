@@ -1,6 +1,8 @@
-package main
+package randomizer
 
 import (
+	"container/list"
+	"math/rand"
 	"testing"
 
 	"github.com/jangler/oracles-randomizer/graph"
@@ -15,8 +17,8 @@ func TestGraph(t *testing.T) {
 
 // check that graph logic is working as expected
 func testSeasonsGraph(t *testing.T) {
-	rom.Init(rom.GameSeasons)
-	r := NewRoute(rom.GameSeasons)
+	rs := rom.NewState(rom.GameSeasons)
+	r := NewRoute(rs, routeOptions{tier: logic.TierHard})
 	g := r.Graph
 
 	checkReach(t, g,
@@ -81,8 +83,8 @@ func testSeasonsGraph(t *testing.T) {
 
 	// make sure that all slots in the game are reachable, given vanilla
 	// progression.
-	for slotName, _ := range rom.ItemSlots {
-		r := NewRoute(rom.GameSeasons)
+	for slotName, _ := range rs.ItemSlots {
+		r := NewRoute(rs, routeOptions{tier: logic.TierHard})
 		g := r.Graph
 		checkReach(t, g, map[string]string{
 			"sword 1":            "d0 sword chest",
@@ -126,12 +128,22 @@ func testSeasonsGraph(t *testing.T) {
 			"hard ore":      "great furnace",
 		}, slotName, false, true)
 	}
+
+	// gale warps can be left out of progression.
+	if g["gale warp"].NumParents() == 0 {
+		t.Errorf("gale warp should be in logic by default")
+	}
+	r = NewRoute(rs, routeOptions{tier: logic.TierHard,
+		tricks: map[string]bool{"gale-warp": false}})
+	if r.Graph["gale warp"].NumParents() != 0 {
+		t.Errorf("gale warp should be out of logic when disabled")
+	}
 }
 
 // check that graph logic is working as expected
 func testAgesGraph(t *testing.T) {
-	rom.Init(rom.GameAges)
-	r := NewRoute(rom.GameAges)
+	rs := rom.NewState(rom.GameAges)
+	r := NewRoute(rs, routeOptions{tier: logic.TierHard})
 	g := r.Graph
 
 	checkReach(t, g, map[string]string{
@@ -222,8 +234,8 @@ func testAgesGraph(t *testing.T) {
 
 	// make sure that all slots in the game are reachable, given vanilla
 	// progression.
-	for slotName, _ := range rom.ItemSlots {
-		r := NewRoute(rom.GameAges)
+	for slotName, _ := range rs.ItemSlots {
+		r := NewRoute(rs, routeOptions{tier: logic.TierHard})
 		g := r.Graph
 		checkReach(t, g, map[string]string{
 			"sword 1":            "starting chest",
@@ -275,7 +287,8 @@ func testAgesGraph(t *testing.T) {
 
 func BenchmarkGraphExplore(b *testing.B) {
 	// init graph
-	r := NewRoute(rom.GameSeasons)
+	rs := rom.NewState(rom.GameSeasons)
+	r := NewRoute(rs, routeOptions{tier: logic.TierHard})
 	b.ResetTimer()
 
 	// explore all items from the d0 sword chest
@@ -319,3 +332,153 @@ func checkReach(t *testing.T, g graph.Graph, parents map[string]string,
 		}
 	}
 }
+
+func TestAuditPools(t *testing.T) {
+	rs := rom.NewState(rom.GameSeasons)
+	r := NewRoute(rs, routeOptions{tier: logic.TierHard})
+	src := rand.New(rand.NewSource(0))
+	itemList, slotList := initRouteInfo(src, r, rom.GameSeasons, 1, false)
+	if problems := auditPools(itemList, slotList); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+
+	// removing the only seed tree slots leaves the seeds nowhere to go.
+	for e := slotList.Front(); e != nil; {
+		next := e.Next()
+		if slotIsSeedTree(e.Value.(*graph.Node).Name) {
+			slotList.Remove(e)
+		}
+		e = next
+	}
+	if problems := auditPools(itemList, slotList); len(problems) == 0 {
+		t.Errorf("expected problems with no seed tree slots")
+	}
+}
+
+func TestPlaceFixedTrees(t *testing.T) {
+	rs := rom.NewState(rom.GameSeasons)
+	r := NewRoute(rs, routeOptions{tier: logic.TierHard})
+	src := rand.New(rand.NewSource(0))
+	itemList, slotList := initRouteInfo(src, r, rom.GameSeasons, 1, false)
+	usedItems, usedSlots := list.New(), list.New()
+
+	// two gale trees needs more gale seeds than the pool has.
+	trees := map[string]string{
+		"horon village seed tree": "gale tree seeds",
+		"north horon seed tree":   "gale tree seeds",
+	}
+	if err := checkFixedTrees(rs, trees); err != nil {
+		t.Fatal(err)
+	}
+	placeFixedTrees(r, trees, itemList, usedItems, slotList, usedSlots)
+
+	for tree, seed := range trees {
+		if !graph.IsNodeInSlice(r.Graph[tree], r.Graph[seed].Parents()) {
+			t.Errorf("%s doesn't have %s", tree, seed)
+		}
+	}
+	if usedItems.Len() != 2 || usedSlots.Len() != 2 {
+		t.Errorf("expected two placements, got %d", usedSlots.Len())
+	}
+	if problems := auditPools(itemList, slotList); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+
+	if checkFixedTrees(rs,
+		map[string]string{"south lynna tree": "ember tree seeds"}) == nil {
+		t.Errorf("expected error for ages tree in seasons")
+	}
+}
+
+func TestReweighJunk(t *testing.T) {
+	rs := rom.NewState(rom.GameAges)
+	r := NewRoute(rs, routeOptions{tier: logic.TierHard})
+	src := rand.New(rand.NewSource(0))
+	itemList, _ := initRouteInfo(src, r, rom.GameAges, 1, false)
+	countItems := func() map[string]int {
+		counts := make(map[string]int)
+		for e := itemList.Front(); e != nil; e = e.Next() {
+			counts[e.Value.(*graph.Node).Name]++
+		}
+		return counts
+	}
+	before := countItems()
+
+	// gasha seeds should all be replaced, and only by other filler.
+	weights := map[string]int{"gasha seed": 0, "rupees, 100": 10}
+	if err := checkJunkWeights(rs, weights); err != nil {
+		t.Fatal(err)
+	}
+	if problems := reweighJunk(src, r, itemList, weights); len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	after := countItems()
+	if after["gasha seed"] != 0 {
+		t.Errorf("want no gasha seeds, got %d", after["gasha seed"])
+	}
+	if after["rupees, 100"] <= before["rupees, 100"] {
+		t.Errorf("want more than %d rupees, 100, got %d",
+			before["rupees, 100"], after["rupees, 100"])
+	}
+	for name, n := range before {
+		if !isFillerName(name) && after[name] != n {
+			t.Errorf("%s: want %d, got %d", name, n, after[name])
+		}
+	}
+
+	zero := make(map[string]int)
+	for name := range after {
+		if isFillerName(name) {
+			zero[name] = 0
+		}
+	}
+	if len(reweighJunk(src, r, itemList, zero)) == 0 {
+		t.Errorf("expected problems with every weight zero")
+	}
+
+	noRupees := make(map[string]int)
+	for name := range poolNames(rs) {
+		if logic.RupeeValues[name] > 0 {
+			noRupees[name] = 0
+		}
+	}
+	for _, weights := range []map[string]int{
+		{"sword 1": 1},
+		{"gasha seed": -1},
+		{"rupees, 1": 1}, // seasons only
+		noRupees,
+	} {
+		if checkJunkWeights(rs, weights) == nil {
+			t.Errorf("%v: expected error", weights)
+		}
+	}
+}
+
+func TestAnimalCompanion(t *testing.T) {
+	regions := map[int][]string{
+		rom.GameSeasons: {"natzu prairie", "natzu river", "natzu wasteland"},
+		rom.GameAges:    {"ricky nuun", "dimitri nuun", "moosh nuun"},
+	}
+
+	for game, names := range regions {
+		rs := rom.NewState(game)
+		for forced := ricky; forced <= moosh; forced++ {
+			r := NewRoute(rs, routeOptions{tier: logic.TierHard})
+			src := rand.New(rand.NewSource(0))
+			if got := rollAnimalCompanion(src, r, game, forced); got != forced {
+				t.Errorf("%s: want companion %d, got %d",
+					GameName(game), forced, got)
+			}
+
+			// only the region for the chosen companion should be connected.
+			for i, name := range names {
+				connected := graph.IsNodeInSlice(r.Graph["start"],
+					r.Graph[name].Parents())
+				if connected != (i+1 == forced) {
+					t.Errorf("%s, companion %d: %s connected = %v",
+						GameName(game), forced, name, connected)
+				}
+			}
+		}
+	}
+}
@@ -1,7 +1,8 @@
-package main
+package randomizer
 
 import (
 	"container/list"
+	"fmt"
 	"math/rand"
 	"sort"
 
@@ -30,20 +31,26 @@ func trySlotRandomItem(r *Route, src *rand.Rand, itemPool,
 
 	// this is the last slot, so it has to open up progression
 	var initialCount int
-	if slotPool.Len() == numUsedSlots+1 && !fillUnused {
+	lastSlot := slotPool.Len() == numUsedSlots+1 && !fillUnused
+	if lastSlot {
 		initialCount = countFunc(r, hard)
 	}
 
-	// try placing an item in the first slot until one fits
+	// try placing an item in the first slot until one fits. the graph doesn't
+	// change between slots, so marks only need to be restored after trying an
+	// item that doesn't work.
 	for es := slotPool.Front(); es != nil; es = es.Next() {
 		slot := es.Value.(*graph.Node)
 
-		r.Graph.ClearMarks()
 		if slot.GetMark(slot, hard) != graph.MarkTrue ||
 			!canAffordSlot(r, slot, hard) {
 			continue
 		}
 
+		var snapshot graph.Snapshot
+		if lastSlot {
+			snapshot = r.Graph.Snapshot()
+		}
 		for ei := itemPool.Front(); ei != nil; ei = ei.Next() {
 			item := ei.Value.(*graph.Node)
 
@@ -53,10 +60,11 @@ func trySlotRandomItem(r *Route, src *rand.Rand, itemPool,
 
 			item.AddParents(slot)
 
-			if slotPool.Len() == numUsedSlots+1 && !fillUnused {
+			if lastSlot {
 				newCount := countFunc(r, hard)
 				if newCount <= initialCount {
 					item.RemoveParent(slot)
+					r.Graph.Restore(snapshot)
 					continue
 				}
 			}
@@ -140,8 +148,43 @@ func itemFitsInSlot(itemNode, slotNode *graph.Node, src *rand.Rand) bool {
 	default:
 		return !slotIsSeedTree(slotNode.Name)
 	}
+}
+
+// auditPools checks that every item in the pool fits in at least one slot and
+// vice versa, and that there are as many items as slots. it returns a
+// description of each problem found, so that impossible combinations of
+// options can be reported instead of retried until the route search gives up.
+func auditPools(itemList, slotList *list.List) []string {
+	problems := make([]string, 0)
+
+	if itemList.Len() != slotList.Len() {
+		problems = append(problems, fmt.Sprintf(
+			"%d items for %d slots", itemList.Len(), slotList.Len()))
+	}
+
+	for ei := itemList.Front(); ei != nil; ei = ei.Next() {
+		item, fits := ei.Value.(*graph.Node), false
+		for es := slotList.Front(); es != nil && !fits; es = es.Next() {
+			fits = itemFitsInSlot(item, es.Value.(*graph.Node), nil)
+		}
+		if !fits {
+			problems = append(problems,
+				fmt.Sprintf("no slot can hold item %q", item.Name))
+		}
+	}
 
-	return true
+	for es := slotList.Front(); es != nil; es = es.Next() {
+		slot, fits := es.Value.(*graph.Node), false
+		for ei := itemList.Front(); ei != nil && !fits; ei = ei.Next() {
+			fits = itemFitsInSlot(ei.Value.(*graph.Node), slot, nil)
+		}
+		if !fits {
+			problems = append(problems,
+				fmt.Sprintf("no item can go in slot %q", slot.Name))
+		}
+	}
+
+	return problems
 }
 
 func slotIsSeedTree(name string) bool {
@@ -158,6 +201,15 @@ func slotIsSeedTree(name string) bool {
 }
 
 func canAffordSlot(r *Route, slot *graph.Node, hard bool) bool {
+	return canAffordSlotWith(r, slot, hard, func(node *graph.Node) bool {
+		return node.GetMark(node, hard) == graph.MarkTrue
+	})
+}
+
+// canAffordSlotWith is canAffordSlot, but uses the given function to decide
+// which nodes are reachable.
+func canAffordSlotWith(r *Route, slot *graph.Node, hard bool,
+	reachable func(*graph.Node) bool) bool {
 	// if it doesn't cost anything, of course it's affordable
 	balance := logic.NodeValues[slot.Name]
 	if balance >= 0 {
@@ -165,19 +217,15 @@ func canAffordSlot(r *Route, slot *graph.Node, hard bool) bool {
 	}
 
 	// in hard mode, 100 rupee manips with shovel are in logic
-	if hard {
-		shovel := r.Graph["shovel"]
-		if shovel.GetMark(shovel, hard) == graph.MarkTrue {
-			return true
-		}
+	if hard && reachable(r.Graph["shovel"]) {
+		return true
 	}
 
 	// otherwise, count the net rupees available to the player
 	balance += r.Rupees
 	for _, node := range r.Graph {
 		value := logic.NodeValues[node.Name]
-		if value != 0 && node != slot &&
-			node.GetMark(node, hard) == graph.MarkTrue {
+		if value != 0 && node != slot && reachable(node) {
 			balance += value
 		}
 	}
@@ -1,11 +1,13 @@
-package main
+package randomizer
 
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/jangler/oracles-randomizer/graph"
 	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/rom"
 )
 
 // getChecks converts a route info into a slice of checks.
@@ -41,11 +43,11 @@ func getSpheres(g graph.Graph, checks map[*graph.Node]*graph.Node,
 	rupees := 0
 	for {
 		sphere := make([]*graph.Node, 0)
-		g.ClearMarks()
+		reachable := g.Reachable(hard)
 
 		// get the set of newly reachable nodes
 		for _, node := range g {
-			if !reached[node] && node.GetMark(node, hard) == graph.MarkTrue {
+			if !reached[node] && reachable[node] {
 				if logic.NodeValues[node.Name] > 0 {
 					rupees += logic.NodeValues[node.Name]
 				}
@@ -87,8 +89,9 @@ func getSpheres(g graph.Graph, checks map[*graph.Node]*graph.Node,
 }
 
 // logSpheres prints item placement by sphere to the summary channel.
-func logSpheres(summary chan string, checks map[*graph.Node]*graph.Node,
-	spheres [][]*graph.Node, filter func(string) bool) {
+func logSpheres(summary chan string, rs *rom.State,
+	checks map[*graph.Node]*graph.Node, spheres [][]*graph.Node,
+	filter func(string) bool) {
 	for i, sphere := range spheres {
 		// get lines first, to make sure there are actual relevant items in
 		// this sphere.
@@ -99,9 +102,12 @@ func logSpheres(summary chan string, checks map[*graph.Node]*graph.Node,
 			}
 			for _, node := range sphere {
 				if node == slot {
-					lines = append(lines, fmt.Sprintf("%-28s <- %s",
-						getNiceName(slot.Name),
-						getNiceName(item.Name)))
+					line := fmt.Sprintf("%-28s <- %s",
+						getNiceName(slot.Name), getNiceName(item.Name))
+					if slotIsVanilla(rs, slot.Name, item.Name) {
+						line += " (vanilla)"
+					}
+					lines = append(lines, line)
 					break
 				}
 			}
@@ -118,6 +124,45 @@ func logSpheres(summary chan string, checks map[*graph.Node]*graph.Node,
 	}
 }
 
+// logPlaythrough prints, for each sphere, the progression items found in the
+// previous sphere and the item slots that they open up.
+func logPlaythrough(summary chan string, rs *rom.State,
+	checks map[*graph.Node]*graph.Node, spheres [][]*graph.Node) {
+	var found []string
+	for i, sphere := range spheres {
+		opened, next := make([]string, 0), make([]string, 0)
+		for _, node := range sphere {
+			if item := checks[node]; item != nil {
+				opened = append(opened, getNiceName(node.Name))
+				if !itemIsJunk(rs, item.Name) {
+					next = append(next, getNiceName(item.Name))
+				}
+			}
+		}
+		if len(opened) > 0 {
+			sort.Strings(opened)
+			if len(found) > 0 {
+				summary <- fmt.Sprintf("sphere %d, after %s:", i,
+					strings.Join(found, "; "))
+			} else {
+				summary <- fmt.Sprintf("sphere %d:", i)
+			}
+			for _, name := range opened {
+				summary <- name
+			}
+			summary <- ""
+		}
+
+		// slots with no items don't end a sphere's worth of progression, so
+		// items carry over until some slots open.
+		if len(opened) > 0 {
+			found = nil
+		}
+		sort.Strings(next)
+		found = append(found, next...)
+	}
+}
+
 // filterUnaffordableNodes removes nodes that the player can't currently afford
 // from the slice, starting with the most expensive ones. it also sorts the
 // slice from least to most expensive.
@@ -146,3 +191,9 @@ func filterUnaffordableNodes(
 
 	return sphere, rupees
 }
+
+// returns true iff the slot contains the same item as in the vanilla game.
+func slotIsVanilla(rs *rom.State, slotName, itemName string) bool {
+	slot := rs.ItemSlots[slotName]
+	return slot != nil && slot.VanillaTreasureName() == itemName
+}
@@ -0,0 +1,158 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/jangler/oracles-randomizer/logic"
+	"github.com/jangler/oracles-randomizer/rom"
+)
+
+// generate a bunch of seeds. routes that couldn't be found are nil.
+func generateSeeds(rs *rom.State, n int, opts routeOptions) []*RouteInfo {
+	threads := runtime.NumCPU()
+	dummyLogf := func(string, ...interface{}) {}
+
+	// search for routes
+	routeChan := make(chan *RouteInfo)
+	for i := 0; i < threads; i++ {
+		go func() {
+			for i := 0; i < n/threads; i++ {
+				seed := uint32(rand.Int())
+				routeChan <- findRoute(context.Background(), rs, seed, false,
+					opts, dummyLogf)
+			}
+		}()
+	}
+
+	// receive found routes
+	routes := make([]*RouteInfo, n/threads*threads)
+	for i := 0; i < len(routes); i++ {
+		routes[i] = <-routeChan
+		fmt.Fprintf(os.Stderr, "%d routes found\n", i+1)
+	}
+
+	return routes
+}
+
+// a count of how many times something happened, for sorting.
+type tally struct {
+	name  string
+	count int
+}
+
+// sortTallies returns the counts in the map from most to least common, with
+// ties broken by name.
+func sortTallies(counts map[string]int) []tally {
+	tallies := make([]tally, 0, len(counts))
+	for name, count := range counts {
+		tallies = append(tallies, tally{name, count})
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		if tallies[i].count != tallies[j].count {
+			return tallies[i].count > tallies[j].count
+		}
+		return tallies[i].name < tallies[j].name
+	})
+	return tallies
+}
+
+// LogStats generates a bunch of seeds and prints information about how often
+// generation fails, how deep progression items are, and which items each slot
+// tends to get.
+func LogStats(game, trials int, tier logic.Tier, tricks map[string]bool,
+	logf func(string, ...interface{})) {
+	rs := rom.NewState(game)
+	routes := generateSeeds(rs, trials,
+		routeOptions{tier: tier, tricks: tricks})
+	hard := tier >= logic.TierHard
+
+	found, attempts := 0, 0
+	sphereCount, requiredCount, requiredDepth := 0, 0, 0
+	meanSpheres := make(map[string]float64)
+	placements := make(map[string]map[string]int)
+	for _, ri := range routes {
+		if ri == nil {
+			attempts += maxTries
+			continue
+		}
+		found++
+		attempts += ri.AttemptCount
+
+		// total spheres
+		checks := getChecks(ri)
+		spheres := getSpheres(ri.Route.Graph, checks, hard)
+		sphereCount += len(spheres)
+		for i, sphere := range spheres {
+			for _, node := range sphere {
+				if !node.IsStep {
+					continue
+				}
+				meanSpheres[node.Name] += float64(i)
+				if item := checks[node]; item != nil &&
+					!itemIsJunk(rs, item.Name) {
+					requiredCount++
+					requiredDepth += i
+				}
+			}
+		}
+
+		for slot, item := range checks {
+			if placements[slot.Name] == nil {
+				placements[slot.Name] = make(map[string]int)
+			}
+			placements[slot.Name][item.Name]++
+		}
+	}
+
+	logf("%d of %d seeds found", found, len(routes))
+	if found == 0 {
+		return
+	}
+	logf("placement attempts: %d (%.1f%% failed)", attempts,
+		100*float64(attempts-found)/float64(attempts))
+	logf("mean spheres per seed: %.1f",
+		float64(sphereCount)/float64(found))
+	logf("mean progression slot sphere: %.1f",
+		float64(requiredDepth)/float64(requiredCount))
+
+	logf("")
+	logf("-- mean sphere by step --")
+	logf("")
+	steps := make([]string, 0, len(meanSpheres))
+	for name := range meanSpheres {
+		steps = append(steps, name)
+	}
+	sort.Strings(steps)
+	for _, name := range steps {
+		logf("%s - %4.1f", getNiceName(name),
+			meanSpheres[name]/float64(found))
+	}
+
+	// the three most common items are enough to show bias toward a slot.
+	logf("")
+	logf("-- most common items by slot --")
+	logf("")
+	slots := make([]string, 0, len(placements))
+	for slot := range placements {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	for _, slot := range slots {
+		tallies := sortTallies(placements[slot])
+		if len(tallies) > 3 {
+			tallies = tallies[:3]
+		}
+		parts := make([]string, len(tallies))
+		for i, t := range tallies {
+			parts[i] = fmt.Sprintf("%s %.0f%%", getNiceName(t.name),
+				100*float64(t.count)/float64(found))
+		}
+		logf("%s - %s", getNiceName(slot), strings.Join(parts, ", "))
+	}
+}
@@ -0,0 +1,30 @@
+package randomizer
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version is the version of the randomizer, which is part of the names of
+// generated files.
+const Version = "3.1.0"
+
+// returns a channel that will write strings to the writer with CRLF line
+// endings. the function will send on the int channel when finished printing.
+func getSummaryChannel(w io.Writer) (chan string, chan int) {
+	c, done := make(chan string), make(chan int)
+
+	go func() {
+		for line := range c {
+			fmt.Fprintf(w, "%s\r\n", line)
+		}
+		done <- 1
+	}()
+
+	// header
+	c <- fmt.Sprintf("oracles randomizer %s", Version)
+	c <- fmt.Sprintf("generated %s", time.Now().Format(time.RFC3339))
+
+	return c, done
+}
@@ -31,14 +31,22 @@ func newAgesRomBanks() *romBanks {
 	return &r
 }
 
-func initAgesEOB() {
+func (s *State) initAgesEOB() {
 	r := newAgesRomBanks()
+	r.mutables = s.codeMutables
 
 	// bank 00
 
 	// don't play any music if the -nomusic flag is given.
-	noMusicFunc := r.appendToBank(0x00, "no music func",
-		"\x67\xfe\x47\x30\x03\x3e\x08\xc9\xf0\xb7\xc9")
+	noMusicFunc := r.appendASM(0x00, "no music func", `
+		ld h,a
+		cp $47
+		jr nc,.sound
+		ld a,$08
+		ret
+	.sound:
+		ldh a,($b7)
+		ret`)
 	r.replace(0x00, 0x0c9a, "no music call",
 		"\x67\xf0\xb7", "\xcd"+noMusicFunc)
 
@@ -54,8 +62,13 @@ func initAgesEOB() {
 		"\x3c\xfe\x11", "\xcd"+makuStateCheck)
 
 	// return z iff the current group and room match c and b.
-	compareRoom := r.appendToBank(0x00, "compare room",
-		"\xfa\x2d\xcc\xb9\xc0\xfa\x30\xcc\xb8\xc9")
+	compareRoom := r.appendASM(0x00, "compare room", `
+		ld a,($cc2d)
+		cp c
+		ret nz
+		ld a,($cc30)
+		cp b
+		ret`)
 
 	// read 2 bytes from bank e at hl into bc.
 	readWord := r.appendToBank(0x00, "read word",
@@ -138,6 +151,7 @@ func initAgesEOB() {
 	// doc/technical.md for a dictionary of the flags.
 	initialGlobalFlags := r.appendToBank(0x03, "initial global flags",
 		"\x0a\x0c\x1d\x20\x23\x2b\x33\x3d\x40\x41\x43\x45\xff")
+	giveStartingItems := appendStartingItems(r, 0x03, 0x171c)
 	skipOpening := r.appendToBank(0x03, "skip opening",
 		"\xe5\x21"+initialGlobalFlags+"\x2a\xfe\xff\x28\x07"+
 			"\xe5\xcd\xf9\x31\xe1\x18\xf4"+ // init global flags
@@ -152,7 +166,7 @@ func initAgesEOB() {
 			"\xea\x6e\xca"+
 			"\x3e\x01\xea\x76\xc8\xea\x38\xc7"+ // room flag 1
 			"\x3e\xc8\xea\x39\xc7\x3e\x02\xea\x6d\xca"+ // other rooms
-			"\xe1\xc9")
+			"\xe1\xc3"+giveStartingItems)
 	r.replace(0x03, 0x6e97, "call skip opening",
 		"\xc3\xf9\x31", "\xc3"+skipOpening)
 
@@ -552,7 +566,7 @@ func initAgesEOB() {
 	// return collection mode in a and e, based on current room. call is in
 	// bank 16, func is in bank 00, body is in bank 06.
 	collectModeTable := r.appendToBank(0x06, "collect mode table",
-		makeAgesCollectModeTable())
+		s.makeAgesCollectModeTable())
 	// maku tree item falls or exists on floor depending on script position.
 	collectMakuTreeFunc := r.appendToBank(0x06, "collect maku tree",
 		"\xfa\x58\xd2\xfe\x84\x1e\x29\xc8\x1e\x0a\xc9")
@@ -645,12 +659,16 @@ func initAgesEOB() {
 			"\x30\x08\x2a\x47\x7e\xe1\x67\x68\xc1\xe9\xe1\xc1\xf1\xc9")
 	r.replace(0x3f, 0x4356, "call load custom sprite",
 		"\xcd\x37\x44", "\xcd"+loadCustomSprite)
+
+	appendInfo(r)
+
+	r.checkBudget()
 }
 
 // makes ages-specific additions to the collection mode table.
-func makeAgesCollectModeTable() string {
+func (s *State) makeAgesCollectModeTable() string {
 	b := new(strings.Builder)
-	table := makeCollectModeTable()
+	table := s.makeCollectModeTable()
 	b.WriteString(table[:len(table)-1]) // strip final ff
 
 	// add eatern symmetry city brother
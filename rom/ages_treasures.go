@@ -2,7 +2,8 @@ package rom
 
 func agesTreasure(id, subID byte, offset uint16,
 	mode, param, text, sprite byte) *Treasure {
-	return &Treasure{id, subID, Addr{0x16, offset}, mode, param, text, sprite}
+	return &Treasure{id: id, subID: subID, addr: Addr{0x16, offset},
+		mode: mode, param: param, text: text, sprite: sprite}
 }
 
 var agesTreasures = map[string]*Treasure{
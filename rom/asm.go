@@ -0,0 +1,506 @@
+package rom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// this file contains a small assembler for the game boy CPU, so that code
+// injections can be written as assembly source instead of hand-assembled byte
+// strings. syntax is close to that of the disassembly: one instruction per
+// line, "label:" definitions, ";" comments, and numbers written as $ff, 0xff,
+// %1010, or decimal. "db" and "dw" emit raw data. expressions can add and
+// subtract numbers and labels, but nothing else.
+
+var (
+	asmReg8 = map[string]byte{
+		"b": 0, "c": 1, "d": 2, "e": 3, "h": 4, "l": 5, "(hl)": 6, "a": 7,
+	}
+	asmReg16   = map[string]byte{"bc": 0, "de": 1, "hl": 2, "sp": 3}
+	asmStack16 = map[string]byte{"bc": 0, "de": 1, "hl": 2, "af": 3}
+	asmConds   = map[string]byte{"nz": 0, "z": 1, "nc": 2, "c": 3}
+	asmALU     = map[string]byte{
+		"add": 0, "adc": 1, "sub": 2, "sbc": 3,
+		"and": 4, "xor": 5, "or": 6, "cp": 7,
+	}
+	asmRotates = map[string]byte{
+		"rlc": 0, "rrc": 1, "rl": 2, "rr": 3,
+		"sla": 4, "sra": 5, "swap": 6, "srl": 7,
+	}
+	asmBitOps  = map[string]byte{"bit": 1, "res": 2, "set": 3}
+	asmImplied = map[string]byte{
+		"nop": 0x00, "halt": 0x76, "di": 0xf3, "ei": 0xfb,
+		"rlca": 0x07, "rrca": 0x0f, "rla": 0x17, "rra": 0x1f,
+		"daa": 0x27, "cpl": 0x2f, "scf": 0x37, "ccf": 0x3f, "reti": 0xd9,
+	}
+)
+
+type assembler struct {
+	pc     uint16
+	labels map[string]uint16
+	final  bool // labels are all known, so undefined ones are errors
+}
+
+// assemble returns the machine code for the given source as a string, as if
+// the code were located at the given address.
+func assemble(org uint16, src string) (string, error) {
+	a := &assembler{labels: make(map[string]uint16)}
+
+	// first pass only finds label addresses; sizes of instructions don't
+	// depend on operand values.
+	if _, err := a.pass(org, src); err != nil {
+		return "", err
+	}
+	a.final = true
+	b, err := a.pass(org, src)
+
+	return string(b), err
+}
+
+func (a *assembler) pass(org uint16, src string) ([]byte, error) {
+	out := make([]byte, 0)
+	a.pc = org
+
+	for i, line := range strings.Split(src, "\n") {
+		if j := strings.IndexByte(line, ';'); j != -1 {
+			line = line[:j]
+		}
+		line = strings.TrimSpace(line)
+
+		for {
+			j := strings.IndexByte(line, ':')
+			if j == -1 || !isAsmIdent(line[:j]) {
+				break
+			}
+			if !a.final {
+				if _, ok := a.labels[line[:j]]; ok {
+					return nil, fmt.Errorf("line %d: duplicate label %s",
+						i+1, line[:j])
+				}
+				a.labels[line[:j]] = a.pc
+			}
+			line = strings.TrimSpace(line[j+1:])
+		}
+		if line == "" {
+			continue
+		}
+
+		mnemonic, rest := line, ""
+		if j := strings.IndexAny(line, " \t"); j != -1 {
+			mnemonic, rest = line[:j], line[j+1:]
+		}
+		args := make([]string, 0, 2)
+		if rest = strings.TrimSpace(rest); rest != "" {
+			for _, arg := range strings.Split(rest, ",") {
+				args = append(args, strings.TrimSpace(arg))
+			}
+		}
+
+		b, err := a.encode(strings.ToLower(mnemonic), args)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		out = append(out, b...)
+		a.pc += uint16(len(b))
+	}
+
+	return out, nil
+}
+
+// returns true if s can be used as a label name.
+func isAsmIdent(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for _, c := range s {
+		if !(c == '_' || c == '.' || (c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns true if the operand is enclosed in parentheses, and the contents.
+func asmMem(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		return strings.ToLower(strings.TrimSpace(s[1 : len(s)-1])), true
+	}
+	return "", false
+}
+
+// evaluates a sum or difference of numbers and labels.
+func (a *assembler) eval(expr string) (int, error) {
+	expr = strings.Replace(expr, " ", "", -1)
+	if expr == "" {
+		return 0, fmt.Errorf("missing operand")
+	}
+
+	total, sign, start := 0, 1, 0
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) && (i == start || (expr[i] != '+' && expr[i] != '-')) {
+			continue
+		}
+
+		v, err := a.evalTerm(expr[start:i])
+		if err != nil {
+			return 0, err
+		}
+		total += sign * v
+
+		if i < len(expr) {
+			sign = 1
+			if expr[i] == '-' {
+				sign = -1
+			}
+		}
+		start = i + 1
+	}
+
+	return total, nil
+}
+
+func (a *assembler) evalTerm(term string) (int, error) {
+	if strings.HasPrefix(term, "-") {
+		v, err := a.evalTerm(term[1:])
+		return -v, err
+	}
+	if strings.HasPrefix(term, "+") {
+		return a.evalTerm(term[1:])
+	}
+
+	var v int64
+	var err error
+	switch {
+	case strings.HasPrefix(term, "$"):
+		v, err = strconv.ParseInt(term[1:], 16, 32)
+	case strings.HasPrefix(term, "0x"):
+		v, err = strconv.ParseInt(term[2:], 16, 32)
+	case strings.HasPrefix(term, "%"):
+		v, err = strconv.ParseInt(term[1:], 2, 32)
+	case term != "" && term[0] >= '0' && term[0] <= '9':
+		v, err = strconv.ParseInt(term, 10, 32)
+	case isAsmIdent(term):
+		addr, ok := a.labels[term]
+		if !ok && a.final {
+			return 0, fmt.Errorf("undefined label %s", term)
+		}
+		return int(addr), nil
+	default:
+		return 0, fmt.Errorf("bad operand %q", term)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("bad number %q", term)
+	}
+	return int(v), nil
+}
+
+func (a *assembler) imm8(expr string) (byte, error) {
+	v, err := a.eval(expr)
+	if err == nil && (v < -0x80 || v > 0xff) {
+		err = fmt.Errorf("%s out of 8-bit range", expr)
+	}
+	return byte(v), err
+}
+
+func (a *assembler) imm16(expr string) ([]byte, error) {
+	v, err := a.eval(expr)
+	if err == nil && (v < -0x8000 || v > 0xffff) {
+		err = fmt.Errorf("%s out of 16-bit range", expr)
+	}
+	return []byte{byte(v), byte(v >> 8)}, err
+}
+
+// returns the offset for a jr from the current instruction to the target.
+func (a *assembler) rel(expr string) (byte, error) {
+	v, err := a.eval(expr)
+	if err != nil || !a.final {
+		return 0, err
+	}
+
+	offset := v - int(a.pc) - 2
+	if offset < -0x80 || offset > 0x7f {
+		return 0, fmt.Errorf("jr to %s out of range", expr)
+	}
+	return byte(offset), nil
+}
+
+// opcode followed by an 8-bit operand.
+func (a *assembler) withImm8(op byte, expr string) ([]byte, error) {
+	n, err := a.imm8(expr)
+	return []byte{op, n}, err
+}
+
+// opcode followed by a 16-bit operand.
+func (a *assembler) withImm16(op byte, expr string) ([]byte, error) {
+	nn, err := a.imm16(expr)
+	return []byte{op, nn[0], nn[1]}, err
+}
+
+func (a *assembler) encode(mnemonic string, args []string) ([]byte, error) {
+	lower := make([]string, len(args))
+	for i, arg := range args {
+		lower[i] = strings.ToLower(strings.Replace(arg, " ", "", -1))
+	}
+
+	if op, ok := asmImplied[mnemonic]; ok && len(args) == 0 {
+		return []byte{op}, nil
+	}
+
+	switch mnemonic {
+	case "db":
+		b := make([]byte, len(args))
+		for i, arg := range args {
+			n, err := a.imm8(arg)
+			if err != nil {
+				return nil, err
+			}
+			b[i] = n
+		}
+		return b, nil
+	case "dw":
+		b := make([]byte, 0, len(args)*2)
+		for _, arg := range args {
+			nn, err := a.imm16(arg)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, nn...)
+		}
+		return b, nil
+	case "stop":
+		if len(args) == 0 {
+			return []byte{0x10, 0x00}, nil
+		}
+	case "ld":
+		if len(args) == 2 {
+			return a.encodeLoad(args, lower)
+		}
+	case "ldi", "ldd":
+		// ldi a,(hl) is the same as ld a,(hl+), etc.
+		if len(args) == 2 {
+			hl := map[string]string{"ldi": "(hl+)", "ldd": "(hl-)"}[mnemonic]
+			for i := range lower {
+				if lower[i] == "(hl)" {
+					args[i], lower[i] = hl, hl
+					return a.encodeLoad(args, lower)
+				}
+			}
+		}
+	case "ldh":
+		if len(args) == 2 {
+			return a.encodeHighLoad(args, lower)
+		}
+	case "inc", "dec":
+		if len(args) == 1 {
+			dec := byte(0)
+			if mnemonic == "dec" {
+				dec = 1
+			}
+			if r, ok := asmReg8[lower[0]]; ok {
+				return []byte{0x04 | r<<3 | dec}, nil
+			}
+			if r, ok := asmReg16[lower[0]]; ok {
+				return []byte{0x03 | r<<4 | dec<<3}, nil
+			}
+		}
+	case "push", "pop":
+		if len(args) == 1 {
+			if r, ok := asmStack16[lower[0]]; ok {
+				if mnemonic == "push" {
+					return []byte{0xc5 | r<<4}, nil
+				}
+				return []byte{0xc1 | r<<4}, nil
+			}
+		}
+	case "jp":
+		switch len(args) {
+		case 1:
+			if lower[0] == "hl" || lower[0] == "(hl)" {
+				return []byte{0xe9}, nil
+			}
+			return a.withImm16(0xc3, args[0])
+		case 2:
+			if cc, ok := asmConds[lower[0]]; ok {
+				return a.withImm16(0xc2|cc<<3, args[1])
+			}
+		}
+	case "call":
+		switch len(args) {
+		case 1:
+			return a.withImm16(0xcd, args[0])
+		case 2:
+			if cc, ok := asmConds[lower[0]]; ok {
+				return a.withImm16(0xc4|cc<<3, args[1])
+			}
+		}
+	case "jr":
+		switch len(args) {
+		case 1:
+			e, err := a.rel(args[0])
+			return []byte{0x18, e}, err
+		case 2:
+			if cc, ok := asmConds[lower[0]]; ok {
+				e, err := a.rel(args[1])
+				return []byte{0x20 | cc<<3, e}, err
+			}
+		}
+	case "ret":
+		switch len(args) {
+		case 0:
+			return []byte{0xc9}, nil
+		case 1:
+			if cc, ok := asmConds[lower[0]]; ok {
+				return []byte{0xc0 | cc<<3}, nil
+			}
+		}
+	case "rst":
+		if len(args) == 1 {
+			v, err := a.eval(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if v&^0x38 == 0 {
+				return []byte{0xc7 | byte(v)}, nil
+			}
+		}
+	}
+
+	if op, ok := asmALU[mnemonic]; ok {
+		if mnemonic == "add" && len(args) == 2 {
+			if r, ok := asmReg16[lower[1]]; ok && lower[0] == "hl" {
+				return []byte{0x09 | r<<4}, nil
+			}
+			if lower[0] == "sp" {
+				return a.withImm8(0xe8, args[1])
+			}
+		}
+
+		// both "sub b" and "sub a,b" are accepted.
+		if len(args) == 2 && lower[0] == "a" {
+			args, lower = args[1:], lower[1:]
+		}
+		if len(args) == 1 {
+			if r, ok := asmReg8[lower[0]]; ok {
+				return []byte{0x80 | op<<3 | r}, nil
+			}
+			if _, ok := asmMem(args[0]); !ok {
+				return a.withImm8(0xc6|op<<3, args[0])
+			}
+		}
+	}
+
+	if op, ok := asmRotates[mnemonic]; ok && len(args) == 1 {
+		if r, ok := asmReg8[lower[0]]; ok {
+			return []byte{0xcb, op<<3 | r}, nil
+		}
+	}
+
+	if op, ok := asmBitOps[mnemonic]; ok && len(args) == 2 {
+		n, err := a.eval(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if r, ok := asmReg8[lower[1]]; ok && n >= 0 && n < 8 {
+			return []byte{0xcb, op<<6 | byte(n)<<3 | r}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid instruction: %s %s",
+		mnemonic, strings.Join(args, ","))
+}
+
+// encodes the many forms of "ld".
+func (a *assembler) encodeLoad(args, lower []string) ([]byte, error) {
+	dst, src := lower[0], lower[1]
+	dstReg, dstIsReg := asmReg8[dst]
+	srcReg, srcIsReg := asmReg8[src]
+	dstMem, dstIsMem := asmMem(args[0])
+	srcMem, srcIsMem := asmMem(args[1])
+	spOffset := strings.Replace(src, " ", "", -1) // "sp+e8"
+
+	switch {
+	case dstIsReg && srcIsReg:
+		if dstReg == 6 && srcReg == 6 {
+			break // that's halt
+		}
+		return []byte{0x40 | dstReg<<3 | srcReg}, nil
+	case dst == "a" && srcIsMem:
+		switch srcMem {
+		case "bc":
+			return []byte{0x0a}, nil
+		case "de":
+			return []byte{0x1a}, nil
+		case "hl+", "hli":
+			return []byte{0x2a}, nil
+		case "hl-", "hld":
+			return []byte{0x3a}, nil
+		case "c":
+			return []byte{0xf2}, nil
+		}
+		return a.withImm16(0xfa, args[1][1:len(args[1])-1])
+	case src == "a" && dstIsMem:
+		switch dstMem {
+		case "bc":
+			return []byte{0x02}, nil
+		case "de":
+			return []byte{0x12}, nil
+		case "hl+", "hli":
+			return []byte{0x22}, nil
+		case "hl-", "hld":
+			return []byte{0x32}, nil
+		case "c":
+			return []byte{0xe2}, nil
+		}
+		return a.withImm16(0xea, args[0][1:len(args[0])-1])
+	case dstIsReg && !srcIsMem:
+		return a.withImm8(0x06|dstReg<<3, args[1])
+	case dst == "sp" && src == "hl":
+		return []byte{0xf9}, nil
+	case dst == "hl" && (strings.HasPrefix(spOffset, "sp+") ||
+		strings.HasPrefix(spOffset, "sp-")):
+		return a.withImm8(0xf8, spOffset[2:])
+	case dstIsMem && src == "sp":
+		return a.withImm16(0x08, args[0][1:len(args[0])-1])
+	}
+
+	if r, ok := asmReg16[dst]; ok && !srcIsMem {
+		return a.withImm16(0x01|r<<4, args[1])
+	}
+
+	return nil, fmt.Errorf("invalid instruction: ld %s", strings.Join(args, ","))
+}
+
+// encodes "ldh", accepting either $ffxx or $xx as the address.
+func (a *assembler) encodeHighLoad(args, lower []string) ([]byte, error) {
+	op, expr := byte(0xf0), ""
+	if mem, ok := asmMem(args[1]); ok && lower[0] == "a" {
+		if mem == "c" {
+			return []byte{0xf2}, nil
+		}
+		expr = args[1][1 : len(args[1])-1]
+	} else if mem, ok := asmMem(args[0]); ok && lower[1] == "a" {
+		if mem == "c" {
+			return []byte{0xe2}, nil
+		}
+		op, expr = 0xe0, args[0][1:len(args[0])-1]
+	} else {
+		return nil, fmt.Errorf("invalid instruction: ldh %s",
+			strings.Join(args, ","))
+	}
+
+	v, err := a.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	if v >= 0xff00 {
+		v -= 0xff00
+	}
+	if v < 0 || v > 0xff {
+		return nil, fmt.Errorf("%s out of range for ldh", expr)
+	}
+
+	return []byte{op, byte(v)}, nil
+}
@@ -0,0 +1,64 @@
+package rom
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	for _, tc := range []struct {
+		src, want string
+	}{
+		{"nop", "\x00"},
+		{"ld a,b", "\x78"},
+		{"ld (hl),$12", "\x36\x12"},
+		{"ld a,(hl+)", "\x2a"},
+		{"ldi a,(hl)", "\x2a"},
+		{"ld (de),a", "\x12"},
+		{"ld a,($c6b4)", "\xfa\xb4\xc6"},
+		{"ld ($c6b4),a", "\xea\xb4\xc6"},
+		{"ld hl,$5129", "\x21\x29\x51"},
+		{"ld hl,sp+2\nld hl,sp-$10", "\xf8\x02\xf8\xf0"},
+		{"ld hl,sp + 0", "\xf8\x00"},
+		{"ld sp,hl\nadd sp,-2", "\xf9\xe8\xfe"},
+		{"ldh a,($ffb5)", "\xf0\xb5"},
+		{"ldh ($b5),a", "\xe0\xb5"},
+		{"inc e\ndec de", "\x1c\x1b"},
+		{"add hl,bc", "\x09"},
+		{"add a,$10\nadd c", "\xc6\x10\x81"},
+		{"cp a,$47\ncp (hl)", "\xfe\x47\xbe"},
+		{"push af\npop bc", "\xf5\xc1"},
+		{"call $171c\ncall nc,$0100", "\xcd\x1c\x17\xd4\x00\x01"},
+		{"jp hl\njp z,$1234", "\xe9\xca\x34\x12"},
+		{"ret\nret c", "\xc9\xd8"},
+		{"rst $18", "\xdf"},
+		{"bit 7,(hl)\nset 0,a\nswap a", "\xcb\x7e\xcb\xc7\xcb\x37"},
+		{"db $01,2,%11\ndw $1234", "\x01\x02\x03\x34\x12"},
+		{"loop: dec b\njr nz,loop", "\x05\x20\xfd"},
+		{"jr .end\nnop\n.end: ret ; comment", "\x18\x01\x00\xc9"},
+		{"ld hl,data+1\nret\ndata: db 0,1", "\x21\x05\x40\xc9\x00\x01"},
+	} {
+		got, err := assemble(0x4000, tc.src)
+		if err != nil {
+			t.Errorf("%q: %v", tc.src, err)
+		} else if got != tc.want {
+			t.Errorf("%q: want %x, got %x", tc.src, tc.want, got)
+		}
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, src := range []string{
+		"ld (hl),(hl)",
+		"ld b,(bc)",
+		"jr nowhere",
+		"foo a",
+		"ld a,$100",
+		"x: nop\nx: nop",
+		"jr far\ndb " + strings.Repeat("0,", 200) + "0\nfar: nop",
+	} {
+		if _, err := assemble(0x4000, src); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
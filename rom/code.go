@@ -1,6 +1,7 @@
 package rom
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 )
@@ -15,22 +16,41 @@ func addrString(addr uint16) string {
 }
 
 // adds code at the given address, returning the length of the byte string.
-func addCode(name string, bank byte, offset uint16, code string) uint16 {
-	codeMutables[name] = MutableString(Addr{bank, offset},
+func (r *romBanks) addCode(name string, bank byte, offset uint16,
+	code string) uint16 {
+	r.mutables[name] = MutableString(Addr{bank, offset},
 		string([]byte{bank}), code)
 	return uint16(len(code))
 }
 
 type romBanks struct {
 	endOfBank []uint16
+	overflow  map[byte]int       // bytes that didn't fit, by bank
+	mutables  map[string]Mutable // where appended code goes
 }
 
-var codeMutables = map[string]Mutable{}
+// returns the address one past the last usable byte in the given bank.
+func bankLimit(bank byte) uint16 {
+	if bank == 0 {
+		return 0x4000
+	}
+	return 0x8000
+}
+
+// freeSpace returns the number of bytes left at the end of the given bank, or
+// zero if the end of the bank is undefined.
+func (r *romBanks) freeSpace(bank byte) int {
+	if r.endOfBank[bank] == 0 {
+		return 0
+	}
+	return int(bankLimit(bank)) - int(r.endOfBank[bank])
+}
 
 // appendToBank appends the given data to the end of the given bank, associates
 // it with the given name, and returns the address of the data as a string such
-// as "\xc8\x3e" for 0x3ec8. it panics if the end of the bank is zero or if the
-// data would overflow the bank.
+// as "\xc8\x3e" for 0x3ec8. it panics if the end of the bank is zero. if the
+// data would overflow the bank, it isn't added, and the overflow is reported
+// by checkBudget.
 func (r *romBanks) appendToBank(bank byte, name, data string) string {
 	eob := r.endOfBank[bank]
 
@@ -38,35 +58,155 @@ func (r *romBanks) appendToBank(bank byte, name, data string) string {
 		panic(fmt.Sprintf("end of bank %02x undefined for %s", bank, name))
 	}
 
-	if eob+uint16(len(data)) > 0x8000 {
-		panic(fmt.Sprintf("not enough space for %s in bank %02x", name, bank))
+	if r.freeSpace(bank) < len(data) {
+		if r.overflow == nil {
+			r.overflow = make(map[byte]int)
+		}
+		r.overflow[bank] += len(data)
+		return addrString(eob)
 	}
 
-	codeMutables[name] = MutableString(Addr{bank, eob}, "", data)
+	r.mutables[name] = MutableString(Addr{bank, eob}, "", data)
 	r.endOfBank[bank] += uint16(len(data))
 
 	return addrString(eob)
 }
 
+// checkBudget panics with a list of the banks that ran out of space, and by how
+// many bytes, if any did.
+func (r *romBanks) checkBudget() {
+	if len(r.overflow) == 0 {
+		return
+	}
+
+	banks := make([]string, 0, len(r.overflow))
+	for bank := 0; bank < len(r.endOfBank); bank++ {
+		if n, ok := r.overflow[byte(bank)]; ok {
+			banks = append(banks, fmt.Sprintf("%02x (%d bytes over)",
+				bank, n-r.freeSpace(byte(bank))))
+		}
+	}
+
+	panic("not enough space in banks: " + strings.Join(banks, ", "))
+}
+
+// appendASM assembles the given source as if it were located at the end of
+// the given bank, then appends the result as appendToBank does. it panics if
+// the source does not assemble.
+func (r *romBanks) appendASM(bank byte, name, src string) string {
+	code, err := assemble(r.endOfBank[bank], src)
+	if err != nil {
+		panic(fmt.Sprintf("assembling %s: %v", name, err))
+	}
+	return r.appendToBank(bank, name, code)
+}
+
 // replace replaces the old data at the given address with the new data, and
 // associates the change with the given name. actual replacement will fail at
 // runtime if the old data does not match the original data in the ROM.
 func (r *romBanks) replace(bank byte, offset uint16, name, old, new string) {
-	codeMutables[name] = MutableString(Addr{bank, offset}, old, new)
+	r.mutables[name] = MutableString(Addr{bank, offset}, old, new)
 }
 
 // replaceMultiple acts as replace, but operates on multiple addresses.
 func (r *romBanks) replaceMultiple(addrs []Addr, name, old, new string) {
-	codeMutables[name] = MutableStrings(addrs, old, new)
+	r.mutables[name] = MutableStrings(addrs, old, new)
+}
+
+// the number of treasures that can be given at the start of the game.
+const maxStartingItems = 8
+
+// appends a table of (ID, param) pairs for treasures to give at the start of
+// the game, and a function that gives them, preserving registers. the table
+// is filled in by SetStartingItems. returns the address of the function.
+func appendStartingItems(r *romBanks, bank byte, giveTreasure uint16) string {
+	table := r.endOfBank[bank]
+	r.appendToBank(bank, "starting items table",
+		strings.Repeat("\xff", maxStartingItems*2+1))
+	return r.appendASM(bank, "give starting items", fmt.Sprintf(`
+		push bc
+		push de
+		push hl
+		ld hl,$%04x
+	.loop:
+		ldi a,(hl)
+		cp $ff
+		jr z,.done
+		ld c,(hl)
+		inc hl
+		push hl
+		call $%04x
+		pop hl
+		jr .loop
+	.done:
+		pop hl
+		pop de
+		pop bc
+		ret`, table, giveTreasure))
+}
+
+// the number of hearts a new file starts with in vanilla, and the most that
+// link can have.
+const (
+	VanillaHearts = 3
+	MaxHearts     = 16
+)
+
+// heart containers have the same treasure ID in both games, and their
+// parameter is the number of quarter hearts they add.
+const heartContainerID = 0x2a
+
+// SetStartingHearts sets the number of hearts that a new file starts with. it
+// takes effect when SetStartingItems is called, and returns an error if the
+// number is outside VanillaHearts to MaxHearts.
+func (s *State) SetStartingHearts(hearts int) error {
+	if hearts < VanillaHearts || hearts > MaxHearts {
+		return fmt.Errorf("starting hearts must be from %d to %d",
+			VanillaHearts, MaxHearts)
+	}
+	s.startingHearts = hearts
+	return nil
+}
+
+// SetStartingItems sets the treasures given to the player when a new file is
+// started. it returns an error if there are too many or if any of them are
+// unknown or fake (like seeds). extra starting hearts are given as one more
+// heart container, which counts toward the limit.
+func (s *State) SetStartingItems(names []string) error {
+	n := len(names)
+	if s.startingHearts > VanillaHearts {
+		n++
+	}
+	if n > maxStartingItems {
+		return fmt.Errorf("can't start with more than %d items",
+			maxStartingItems)
+	}
+
+	mut := s.codeMutables["starting items table"].(*MutableRange)
+	mut.New = bytes.Repeat([]byte{0xff}, maxStartingItems*2+1)
+	for i, name := range names {
+		t := s.Treasures[name]
+		if t == nil || t.addr.offset == 0 {
+			return fmt.Errorf("can't start with %s", name)
+		}
+		mut.New[i*2], mut.New[i*2+1] = t.id, t.param
+	}
+	if s.startingHearts > VanillaHearts {
+		i := len(names)
+		mut.New[i*2] = heartContainerID
+		mut.New[i*2+1] = byte((s.startingHearts - VanillaHearts) * 4)
+	}
+
+	return nil
 }
 
 // returns a byte table of (group, room, collect mode) entries for randomized
 // items. in ages, a mode >7f means to use &7f as an index to a jump table for
 // special cases.
-func makeCollectModeTable() string {
+func (s *State) makeCollectModeTable() string {
 	b := new(strings.Builder)
 
-	for _, slot := range ItemSlots {
+	for _, slot := range s.ItemSlots {
 		// trees and slots where it doesn't matter (shops, rod)
 		if slot.collectMode == 0 {
 			continue
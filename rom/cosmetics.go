@@ -0,0 +1,23 @@
+package rom
+
+import (
+	"fmt"
+)
+
+// this file is for options that only change how the game looks. none of them
+// should affect logic or item placement.
+
+// TunicColors are the names of Link's possible tunic colors, in order of value.
+var TunicColors = []string{"green", "blue", "red", "gold"}
+
+// SetTunicColor sets Link's tunic color (green, blue, red, or gold; value from 0-3)
+func (s *State) SetTunicColor(color int) {
+	for i := 0; i <= 9; i++ { // Object palettes
+		var mut = s.varMutables["object tunic color "+fmt.Sprint(i)].(*MutableRange)
+		mut.New[0] = mut.Old[0] | byte(color)
+	}
+	for i := 0; i <= 21; i++ { // File select sprites
+		var mut = s.varMutables["file tunic color "+fmt.Sprint(i)].(*MutableRange)
+		mut.New[0] = mut.Old[0] | byte(color)
+	}
+}
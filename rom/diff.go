@@ -0,0 +1,109 @@
+package rom
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A Change is a single byte that would be changed by Mutate.
+type Change struct {
+	Name     string // name of the mutable responsible for the change
+	Offset   int    // offset in the ROM file
+	Old, New byte
+}
+
+// String returns the change in the form "name: bank:addr old -> new".
+func (c Change) String() string {
+	bank, addr := c.Offset/bankSize, c.Offset%bankSize
+	if bank > 0 {
+		addr += bankSize
+	}
+	return fmt.Sprintf("%s: %02x:%04x %02x -> %02x",
+		c.Name, bank, addr, c.Old, c.New)
+}
+
+// Diff returns the bytes that Mutate would change in the given ROM data,
+// without modifying it. Changes are listed in the order that Mutate applies
+// them, so later changes to the same offset take precedence.
+func (s *State) Diff(b []byte) ([]Change, error) {
+	s.prepareMutables()
+
+	w := make([]byte, len(b))
+	copy(w, b)
+	changes := make([]Change, 0)
+
+	for _, nm := range s.orderedMutables() {
+		offsets := mutableOffsets(nm.mut)
+		old := make([]byte, len(offsets))
+		for i, offset := range offsets {
+			old[i] = w[offset]
+		}
+		if err := nm.mut.Mutate(w); err != nil {
+			return nil, err
+		}
+		for i, offset := range offsets {
+			if w[offset] != old[i] {
+				changes = append(changes, Change{nm.name, offset, old[i],
+					w[offset]})
+			}
+		}
+	}
+
+	// compass data and the header aren't mutables, so compare the whole
+	// buffer.
+	for _, step := range []struct {
+		name string
+		set  func([]byte)
+	}{
+		{"compass data", s.setCompassData},
+		{"header", s.setHeader},
+	} {
+		before := make([]byte, len(w))
+		copy(before, w)
+		step.set(w)
+		if !bytes.Equal(before, w) {
+			for i := range w {
+				if w[i] != before[i] {
+					changes = append(changes, Change{step.name, i,
+						before[i], w[i]})
+				}
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// returns the ROM offsets that the mutable writes to.
+func mutableOffsets(m Mutable) []int {
+	offsets := make([]int, 0)
+
+	switch m := m.(type) {
+	case *MutableRange:
+		for _, addr := range m.Addrs {
+			offset := addr.fullOffset()
+			for i := range m.New {
+				offsets = append(offsets, offset+i)
+			}
+		}
+	case *MutableSlot:
+		for _, addrs := range [][]Addr{
+			m.idAddrs, m.subIDAddrs, m.paramAddrs, m.textAddrs} {
+			for _, addr := range addrs {
+				offsets = append(offsets, addr.fullOffset())
+			}
+		}
+		for _, addr := range m.gfxAddrs {
+			offset := addr.fullOffset()
+			offsets = append(offsets, offset, offset+1, offset+2)
+		}
+		offsets = append(offsets, mutableOffsets(m.Treasure)...)
+	case *Treasure:
+		if m.addr.offset != 0 {
+			offset := m.addr.fullOffset()
+			offsets = append(offsets, offset, offset+1, offset+2, offset+3)
+		}
+	}
+
+	return offsets
+}
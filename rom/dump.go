@@ -0,0 +1,125 @@
+package rom
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// addresses of the treasure data tables, which are indexed by ID. if the first
+// byte of an entry has bit 7 set, the next two bytes point to a table of
+// entries for that ID, indexed by sub ID.
+var treasureTableAddrs = map[int]Addr{
+	GameSeasons: {0x15, 0x5129},
+	GameAges:    {0x16, 0x5332},
+}
+
+// the maximum number of sub IDs to read for the last table of sub IDs, since
+// its end can't be determined from other pointers.
+const maxTrailingSubIDs = 8
+
+// DumpTables returns the treasure data table and the contents of each item
+// slot in the given ROM, formatted like the package's own data, so that new
+// data can be bootstrapped from an unfamiliar ROM.
+func DumpTables(b []byte, game int) string {
+	s := NewState(game)
+	buf := new(bytes.Buffer)
+	s.dumpTreasureTable(buf, b)
+	fmt.Fprintln(buf)
+	s.dumpSlotContents(buf, b)
+	return buf.String()
+}
+
+// writes one line per treasure ID and sub ID, in the format of the
+// seasonsTreasure and agesTreasure functions.
+func (s *State) dumpTreasureTable(buf *bytes.Buffer, b []byte) {
+	base := treasureTableAddrs[s.Game]
+	funcName := map[int]string{
+		GameSeasons: "seasonsTreasure",
+		GameAges:    "agesTreasure",
+	}[s.Game]
+
+	// the ID table ends where the first sub ID table begins.
+	read := func(offset uint16) []byte {
+		i := (&Addr{base.bank, offset}).fullOffset()
+		return b[i : i+4]
+	}
+	subTables := make([]int, 0)
+	end := uint16(0x8000)
+	for offset := base.offset; offset < end; offset += 4 {
+		entry := read(offset)
+		if entry[0]&0x80 != 0 {
+			ptr := uint16(entry[1]) | uint16(entry[2])<<8
+			subTables = append(subTables, int(ptr))
+			if ptr < end {
+				end = ptr
+			}
+		}
+	}
+	sort.Ints(subTables)
+
+	names := make(map[[2]byte]string)
+	for name, t := range s.Treasures {
+		if t.addr.offset == 0 {
+			continue // fake treasures like seeds use a different ID space
+		}
+		key := [2]byte{t.id, t.subID}
+		if names[key] == "" || name < names[key] {
+			names[key] = name
+		}
+	}
+
+	write := func(id, subID byte, offset uint16) {
+		entry := read(offset)
+		name := names[[2]byte{id, subID}]
+		if name == "" {
+			name = "?"
+		}
+		fmt.Fprintf(buf, "%-18s %s(0x%02x, 0x%02x, 0x%04x, "+
+			"0x%02x, 0x%02x, 0x%02x, 0x%02x),\n", fmt.Sprintf("%q:", name),
+			funcName, id, subID, offset, entry[0], entry[1], entry[2],
+			entry[3])
+	}
+
+	fmt.Fprintf(buf, "// treasure table at %02x:%04x\n", base.bank, base.offset)
+	for id := 0; base.offset+uint16(id)*4 < end; id++ {
+		offset := base.offset + uint16(id)*4
+		entry := read(offset)
+		if entry[0]&0x80 == 0 {
+			write(byte(id), 0, offset)
+			continue
+		}
+
+		// sub ID tables are assumed to be contiguous.
+		ptr := int(uint16(entry[1]) | uint16(entry[2])<<8)
+		count := maxTrailingSubIDs
+		i := sort.SearchInts(subTables, ptr+1)
+		if i < len(subTables) {
+			count = (subTables[i] - ptr) / 4
+		}
+		for subID := 0; subID < count; subID++ {
+			write(byte(id), byte(subID), uint16(ptr+subID*4))
+		}
+	}
+}
+
+// writes the treasure found at each slot's ID address.
+func (s *State) dumpSlotContents(buf *bytes.Buffer, b []byte) {
+	s.setDynamicSlotAddrs()
+
+	names := make([]string, 0, len(s.ItemSlots))
+	for name := range s.ItemSlots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(buf, "// item slots")
+	for _, name := range names {
+		treasure := s.readSlotTreasure(b, s.ItemSlots[name])
+		if treasure == "" {
+			treasure = "?"
+		}
+		fmt.Fprintf(buf, "%-30s treasureName: %q,\n",
+			fmt.Sprintf("%q:", name), treasure)
+	}
+}
@@ -0,0 +1,109 @@
+package rom
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// interior runs of padding shorter than this aren't worth reporting.
+const minFreeRun = 0x40
+
+// A FreeRegion is a run of identical 0x00 or 0xff bytes in a bank, which is
+// probably unused.
+type FreeRegion struct {
+	Bank       byte
+	Start, End uint16 // end is exclusive
+	Trailing   bool   // region runs to the end of the bank
+}
+
+// FindFreeSpace returns the padding at the end of each bank of the ROM, and
+// other long runs of padding that don't overlap any of the state's
+// mutables. padding isn't necessarily free, since some data tables are also
+// zeroes, so interior regions should be checked before use.
+func (s *State) FindFreeSpace(b []byte) []FreeRegion {
+	known := make([]bool, len(b))
+	for _, m := range s.getAllMutables() {
+		if mut, ok := m.(*MutableRange); ok {
+			size := len(mut.Old)
+			if len(mut.New) > size {
+				size = len(mut.New)
+			}
+			for _, addr := range mut.Addrs {
+				offset := addr.fullOffset()
+				for i := offset; i < offset+size && i < len(b); i++ {
+					known[i] = true
+				}
+			}
+		}
+	}
+
+	regions := make([]FreeRegion, 0)
+	for bank := 0; bank < len(b)/bankSize; bank++ {
+		base, start := bankSize*bank, uint16(0x4000)
+		if bank == 0 {
+			start = 0
+		}
+
+		// scan the bank in runs of identical bytes.
+		for i := 0; i < bankSize; {
+			j := i + 1
+			for j < bankSize && b[base+j] == b[base+i] {
+				j++
+			}
+
+			if b[base+i] == 0x00 || b[base+i] == 0xff {
+				trailing, free := j == bankSize, true
+				for k := base + i; k < base+j; k++ {
+					free = free && !known[k]
+				}
+				if trailing || (free && j-i >= minFreeRun) {
+					regions = append(regions, FreeRegion{
+						Bank:     byte(bank),
+						Start:    start + uint16(i),
+						End:      start + uint16(j),
+						Trailing: trailing,
+					})
+				}
+			}
+
+			i = j
+		}
+	}
+
+	return regions
+}
+
+// FreeSpaceReport lists the free regions of the ROM, and compares the padding
+// at the end of each bank to where the package starts appending code.
+func FreeSpaceReport(b []byte, game int) string {
+	var banks *romBanks
+	if game == GameSeasons {
+		banks = newSeasonsRomBanks()
+	} else {
+		banks = newAgesRomBanks()
+	}
+
+	buf := new(bytes.Buffer)
+	for _, region := range NewState(game).FindFreeSpace(b) {
+		fmt.Fprintf(buf, "%02x:%04x-%04x  %5d bytes",
+			region.Bank, region.Start, region.End-1,
+			int(region.End)-int(region.Start))
+
+		eob := banks.endOfBank[region.Bank]
+		switch {
+		case !region.Trailing:
+			fmt.Fprint(buf, "  (interior)")
+		case eob == 0:
+			fmt.Fprint(buf, "  (unused by randomizer)")
+		case eob < region.Start:
+			fmt.Fprintf(buf, "  (WARNING: randomizer appends at %04x, "+
+				"before end of data)", eob)
+		case eob > region.Start:
+			fmt.Fprintf(buf, "  (randomizer appends at %04x; %d bytes "+
+				"unclaimed)", eob, eob-region.Start)
+		}
+		fmt.Fprintln(buf)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,77 @@
+package rom
+
+import (
+	"fmt"
+)
+
+// addresses in the cartridge header. the title is followed by the
+// manufacturer code, which is left alone so the game can still be identified.
+const (
+	titleAddr          = 0x134
+	titleLength        = 11
+	licenseeAddr       = 0x14b
+	headerChecksumAddr = 0x14d
+	globalChecksumAddr = 0x14e
+)
+
+// SetTitle replaces the game title in the cartridge header. the title can be
+// up to 11 uppercase ASCII characters; an empty title keeps the vanilla one.
+func (s *State) SetTitle(title string) error {
+	if len(title) > titleLength {
+		return fmt.Errorf("title can be at most %d characters", titleLength)
+	}
+	for _, c := range title {
+		if c < ' ' || c > '_' {
+			return fmt.Errorf("invalid title character %q; use uppercase "+
+				"letters, digits, spaces, or punctuation", c)
+		}
+	}
+	s.title = title
+	return nil
+}
+
+// SetLicensee replaces the old licensee code in the cartridge header. a
+// negative code keeps the vanilla one.
+func (s *State) SetLicensee(code int) {
+	s.licensee = code
+}
+
+// setHeader writes the title and licensee to the ROM, if set, then updates
+// the header and global checksums to match the data.
+func (s *State) setHeader(b []byte) {
+	if s.title != "" {
+		for i := 0; i < titleLength; i++ {
+			b[titleAddr+i] = 0
+		}
+		copy(b[titleAddr:], s.title)
+	}
+	if s.licensee >= 0 {
+		b[licenseeAddr] = byte(s.licensee)
+	}
+
+	b[headerChecksumAddr] = headerChecksum(b)
+	sum := globalChecksum(b)
+	b[globalChecksumAddr], b[globalChecksumAddr+1] = byte(sum>>8), byte(sum)
+}
+
+// headerChecksum returns the checksum of the bytes from the title to the mask
+// ROM version, which the boot ROM checks.
+func headerChecksum(b []byte) byte {
+	var x byte
+	for _, v := range b[titleAddr:headerChecksumAddr] {
+		x = x - v - 1
+	}
+	return x
+}
+
+// globalChecksum returns the sum of every byte in the ROM except the global
+// checksum itself.
+func globalChecksum(b []byte) uint16 {
+	var sum uint16
+	for i, v := range b {
+		if i != globalChecksumAddr && i != globalChecksumAddr+1 {
+			sum += uint16(v)
+		}
+	}
+	return sum
+}
@@ -0,0 +1,61 @@
+package rom
+
+import (
+	"bytes"
+	"strings"
+)
+
+// randomized ROMs carry a block of text describing the randomizer version,
+// seed, and settings, so that they can be identified without their log files.
+// the block starts with a marker so that it can be found regardless of which
+// bank it ended up in.
+const (
+	infoMarker = "ORACLES RANDO\x00\x00\x00"
+	infoSize   = 512 // including marker
+)
+
+// appends an empty info block to whichever bank has the most free space. the
+// text is filled in by SetInfo.
+func appendInfo(r *romBanks) {
+	bank := byte(0)
+	for i := 1; i < len(r.endOfBank); i++ {
+		if r.freeSpace(byte(i)) > r.freeSpace(bank) {
+			bank = byte(i)
+		}
+	}
+	r.appendToBank(bank, "randomizer info",
+		infoMarker+strings.Repeat("\x00", infoSize-len(infoMarker)))
+}
+
+// SetInfo sets the text of the ROM's info block. text that doesn't fit is cut
+// off at the end of the last line that does.
+func (s *State) SetInfo(text string) {
+	max := infoSize - len(infoMarker) - 1 // keep a terminator
+	if len(text) > max {
+		text = text[:max]
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			text = text[:i]
+		}
+	}
+
+	mut := s.codeMutables["randomizer info"].(*MutableRange)
+	copy(mut.New[len(infoMarker):], make([]byte, infoSize-len(infoMarker)))
+	copy(mut.New[len(infoMarker):], text)
+}
+
+// ReadInfo returns the text of the info block in the ROM data, and false if
+// there isn't one.
+func ReadInfo(b []byte) (string, bool) {
+	i := bytes.Index(b, []byte(infoMarker))
+	if i < 0 {
+		return "", false
+	}
+	text := b[i+len(infoMarker):]
+	if len(text) > infoSize-len(infoMarker) {
+		text = text[:infoSize-len(infoMarker)]
+	}
+	if end := bytes.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+	return string(text), true
+}
@@ -31,16 +31,24 @@ func (ms *MutableSlot) Mutate(b []byte) error {
 	for _, addr := range ms.textAddrs {
 		b[addr.fullOffset()] = ms.Treasure.text
 	}
-	for _, addr := range ms.gfxAddrs {
-		gfx := itemGfx[FindTreasureName(ms.Treasure)]
-		for i := 0; i < 3; i++ {
-			b[addr.fullOffset()+i] = byte(gfx >> (8 * uint(2-i)))
+	if len(ms.gfxAddrs) > 0 {
+		gfx := ms.Treasure.gfx
+		for _, addr := range ms.gfxAddrs {
+			for i := 0; i < 3; i++ {
+				b[addr.fullOffset()+i] = byte(gfx >> (8 * uint(2-i)))
+			}
 		}
 	}
 
 	return ms.Treasure.Mutate(b)
 }
 
+// VanillaTreasureName returns the name of the treasure in the slot in the
+// vanilla game.
+func (ms *MutableSlot) VanillaTreasureName() string {
+	return ms.treasureName
+}
+
 // helper function for MutableSlot.Check
 func check(b []byte, addr Addr, value byte) error {
 	if b[addr.fullOffset()] != value {
@@ -72,12 +80,14 @@ func (ms *MutableSlot) Check(b []byte) error {
 			return err
 		}
 	}
-	for _, addr := range ms.gfxAddrs {
-		gfx := itemGfx[FindTreasureName(ms.Treasure)]
-		for i := uint16(0); i < 3; i++ {
-			addr := Addr{addr.bank, addr.offset + i}
-			if err := check(b, addr, byte(gfx>>(8*(2-i)))); err != nil {
-				return err
+	if len(ms.gfxAddrs) > 0 {
+		gfx := ms.Treasure.gfx
+		for _, addr := range ms.gfxAddrs {
+			for i := uint16(0); i < 3; i++ {
+				addr := Addr{addr.bank, addr.offset + i}
+				if err := check(b, addr, byte(gfx>>(8*(2-i)))); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -104,4 +114,19 @@ func basicSlot(treasure string, bank byte, idOffset, subIDOffset uint16,
 	}
 }
 
-var ItemSlots map[string]*MutableSlot
+// returns a copy of the slots that doesn't share any data with them. the
+// copies' treasures are left nil.
+func copySlots(m map[string]*MutableSlot) map[string]*MutableSlot {
+	c := make(map[string]*MutableSlot, len(m))
+	for k, v := range m {
+		slot := *v
+		slot.Treasure = nil
+		slot.idAddrs = append([]Addr(nil), v.idAddrs...)
+		slot.subIDAddrs = append([]Addr(nil), v.subIDAddrs...)
+		slot.paramAddrs = append([]Addr(nil), v.paramAddrs...)
+		slot.textAddrs = append([]Addr(nil), v.textAddrs...)
+		slot.gfxAddrs = append([]Addr(nil), v.gfxAddrs...)
+		c[k] = &slot
+	}
+	return c
+}
@@ -0,0 +1,73 @@
+package rom
+
+// the start of each group's room flags, from doc/technical.md. groups not
+// listed aren't known.
+var roomFlagsBases = map[byte]uint16{
+	0: 0xc700,
+	1: 0xc800,
+	2: 0xc800,
+	4: 0xc900,
+	5: 0xca00,
+}
+
+// the room flag bit commonly set when a room's treasure is obtained.
+const roomTreasureMask = 0x20
+
+// A SlotFlag identifies the room flag that's set when a slot's item is
+// collected. Addr is zero if the group's flags aren't known, and the whole
+// flag is omitted for slots like shops and seed trees, which don't set one.
+type SlotFlag struct {
+	Group byte   `json:"group"`
+	Room  byte   `json:"room"`
+	Addr  uint16 `json:"addr,omitempty"`
+	Mask  byte   `json:"mask"`
+}
+
+// A TreasureFlag identifies the flag that's set when link has a treasure.
+type TreasureFlag struct {
+	ID   byte   `json:"id"`
+	Addr uint16 `json:"addr"`
+	Mask byte   `json:"mask"`
+}
+
+// A MemoryMap maps slots and treasures to the RAM flags that show that
+// they've been collected, for use by emulator auto-trackers.
+type MemoryMap struct {
+	Slots     map[string]*SlotFlag     `json:"slots"`
+	Treasures map[string]*TreasureFlag `json:"treasures"`
+}
+
+// MakeMemoryMap returns the memory map for the game's slots and treasures.
+func MakeMemoryMap(game int) *MemoryMap {
+	s := NewState(game)
+	m := &MemoryMap{
+		Slots:     make(map[string]*SlotFlag, len(s.ItemSlots)),
+		Treasures: make(map[string]*TreasureFlag, len(s.Treasures)),
+	}
+
+	for name, slot := range s.ItemSlots {
+		if slot.collectMode == collectNil {
+			continue
+		}
+		flag := &SlotFlag{Group: slot.group, Room: slot.room,
+			Mask: roomTreasureMask}
+		if base, ok := roomFlagsBases[slot.group]; ok {
+			flag.Addr = base + uint16(slot.room)
+		}
+		m.Slots[name] = flag
+	}
+
+	base := uint16(0xc69a)
+	if game == GameSeasons {
+		base = 0xc692
+	}
+	for name, t := range s.Treasures {
+		m.Treasures[name] = &TreasureFlag{
+			ID:   t.id,
+			Addr: base + uint16(t.id/8),
+			Mask: 1 << (t.id % 8),
+		}
+	}
+
+	return m
+}
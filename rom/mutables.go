@@ -69,6 +69,24 @@ func (mr *MutableRange) Mutate(b []byte) error {
 	return nil
 }
 
+// returns a copy of the range that doesn't share any data with it.
+func (mr *MutableRange) copy() *MutableRange {
+	return &MutableRange{
+		Addrs: append([]Addr{}, mr.Addrs...),
+		Old:   append([]byte{}, mr.Old...),
+		New:   append([]byte{}, mr.New...),
+	}
+}
+
+// returns a copy of the map and its mutables, which must all be ranges.
+func copyMutables(m map[string]Mutable) map[string]Mutable {
+	c := make(map[string]Mutable, len(m))
+	for k, v := range m {
+		c[k] = v.(*MutableRange).copy()
+	}
+	return c
+}
+
 // Check verifies that the range matches the given ROM data.
 func (mr *MutableRange) Check(b []byte) error {
 	for _, addr := range mr.Addrs {
@@ -84,76 +102,91 @@ func (mr *MutableRange) Check(b []byte) error {
 }
 
 // SetMusic sets music on or off in the modified ROM.
-func SetMusic(music bool) {
+func (s *State) SetMusic(music bool) {
 	if music {
-		mut := codeMutables["no music call"].(*MutableRange)
+		mut := s.codeMutables["no music call"].(*MutableRange)
 		mut.New = mut.Old
 	}
 }
 
 // SetTreewarp sets treewarp on or off in the modified ROM.
-func SetTreewarp(treewarp bool) {
+func (s *State) SetTreewarp(treewarp bool) {
 	if !treewarp {
-		mut := codeMutables["tree warp jump"].(*MutableRange)
+		mut := s.codeMutables["tree warp jump"].(*MutableRange)
 		mut.New = mut.Old
 	}
 }
 
+// SetCompassHint sets a function that picks treasures for the compass to beep
+// for in addition to boss keys. nil means boss keys only.
+func (s *State) SetCompassHint(hint func(treasureName string) bool) {
+	s.compassHint = hint
+}
+
+// the number of sparkles on the seasons treasure map.
+const TreasureMapSparkles = 4
+
+// TreasureMapJewels are the jewels that the treasure map's sparkles are tied
+// to, in order.
+var TreasureMapJewels = []string{"round", "pyramid", "square", "x-shaped"}
+
+// SetTreasureMapSlots makes sparkles on the treasure map mark the named slots
+// instead of the jewels, in order. sparkles past the end of the list still mark
+// jewels. each sparkle still goes away when its jewel is found, not when the
+// marked slot is checked, since the game ties sparkles to jewel flags.
+func (s *State) SetTreasureMapSlots(names []string) error {
+	if len(names) > TreasureMapSparkles {
+		return fmt.Errorf("treasure map can't mark more than %d slots",
+			TreasureMapSparkles)
+	}
+	for _, name := range names {
+		if s.ItemSlots[name] == nil {
+			return fmt.Errorf("no such slot: %s", name)
+		}
+	}
+	s.treasureMapSlots = names
+	return nil
+}
+
 // SetAnimal sets the flute type and Natzu region type based on a companion
 // number 1 to 3.
-func SetAnimal(companion int) {
-	varMutables["animal region"].(*MutableRange).New =
+func (s *State) SetAnimal(companion int) {
+	s.varMutables["animal region"].(*MutableRange).New =
 		[]byte{byte(companion + 0x0a)}
 
 	// ages
-	if varMutables["flute palette"] != nil {
-		mut := varMutables["flute palette"].(*MutableRange)
+	if s.varMutables["flute palette"] != nil {
+		mut := s.varMutables["flute palette"].(*MutableRange)
 		mut.New[0] = byte(0x10*(4-companion) + 3)
 	}
 }
 
-// SetTunicColor sets Link's tunic color (green, blue, red, or gold; value from 0-3)
-func SetTunicColor(color int) {
-	for i := 0; i <= 9; i++ { // Object palettes
-		var mut = varMutables["object tunic color " + fmt.Sprint(i)].(*MutableRange)
-		mut.New[0] = mut.Old[0] | byte(color)
-	}
-	for i := 0; i <= 21; i++ { // File select sprites
-		var mut = varMutables["file tunic color " + fmt.Sprint(i)].(*MutableRange)
-		mut.New[0] = mut.Old[0] | byte(color)
+// get a collated map of all mutables
+func (s *State) getAllMutables() map[string]Mutable {
+	// reverse lookup table, to avoid searching the treasure map per slot
+	treasureNames := make(map[*Treasure]string, len(s.Treasures))
+	for k, v := range s.Treasures {
+		treasureNames[v] = k
 	}
-}
-
-// these mutables have fixed addresses and don't reference other mutables. try
-// to generally order them by address, unless a grouping between mutables in
-// different banks makes more sense.
-var fixedMutables map[string]Mutable
 
-// like the item slots, these are (usually) no-ops until the randomizer touches
-// them. these are also fixed, but generally need to have their values set
-// elsewhere in order to do anything.
-var varMutables map[string]Mutable
-
-// get a collated map of all mutables
-func getAllMutables() map[string]Mutable {
-	slotMutables := make(map[string]Mutable)
-	treasureMutables := make(map[string]Mutable)
-	for k, v := range ItemSlots {
+	slotMutables := make(map[string]Mutable, len(s.ItemSlots))
+	treasureMutables := make(map[string]Mutable, len(s.ItemSlots))
+	for k, v := range s.ItemSlots {
 		if v.Treasure == nil {
 			log.Fatalf("treasure named %s for %s is nil", v.treasureName, k)
 		}
 		if v.Treasure.addr.offset != 0 {
-			treasureMutables[FindTreasureName(v.Treasure)] = v.Treasure
+			treasureMutables[treasureNames[v.Treasure]] = v.Treasure
 		}
 		slotMutables[k] = v
 	}
 
 	mutableSets := []map[string]Mutable{
-		fixedMutables,
+		s.fixedMutables,
 		treasureMutables,
 		slotMutables,
-		varMutables,
-		codeMutables,
+		s.varMutables,
+		s.codeMutables,
 	}
 
 	// initialize master map w/ adequate capacity
@@ -0,0 +1,134 @@
+package rom
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"sort"
+)
+
+// A Report describes the contents of a randomized ROM as compared to what the
+// package would write to it.
+type Report struct {
+	Sum []byte
+
+	// treasure name found in each slot, or "" if the data there doesn't match
+	// any known treasure.
+	Slots map[string]string
+
+	// code that is still in its vanilla state, which is expected for some
+	// patches depending on options, and code that matches neither the vanilla
+	// nor the modified data.
+	Unapplied, Mismatched []string
+}
+
+// MakeReport reads slot contents and code patches from the given ROM data.
+func MakeReport(b []byte, game int) *Report {
+	s := NewState(game)
+	sum := sha1.Sum(b)
+	r := &Report{
+		Sum:        sum[:],
+		Slots:      make(map[string]string, len(s.ItemSlots)),
+		Unapplied:  make([]string, 0),
+		Mismatched: make([]string, 0),
+	}
+
+	s.setDynamicSlotAddrs()
+	for name, slot := range s.ItemSlots {
+		r.Slots[name] = s.readSlotTreasure(b, slot)
+	}
+
+	// only code mutables are checked, since the others depend on placement.
+	for _, name := range orderedKeys(s.codeMutables) {
+		mut, ok := s.codeMutables[name].(*MutableRange)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case rangeMatches(b, mut, mut.New):
+		case rangeMatches(b, mut, mut.Old):
+			r.Unapplied = append(r.Unapplied, name)
+		default:
+			r.Mismatched = append(r.Mismatched, name)
+		}
+	}
+
+	return r
+}
+
+// returns the name of the treasure whose ID and sub ID are at the slot's first
+// addresses. if more than one treasure matches, the first in alphabetical
+// order is used. slots for fake treasures (seed trees) only match other fake
+// treasures, since their IDs are in a different space.
+func (s *State) readSlotTreasure(b []byte, slot *MutableSlot) string {
+	if len(slot.idAddrs) == 0 || slot.idAddrs[0].offset == 0 {
+		return ""
+	}
+	id := b[slot.idAddrs[0].fullOffset()]
+	fake := s.Treasures[slot.treasureName].addr.offset == 0
+
+	names := make([]string, 0, len(s.Treasures))
+	for name := range s.Treasures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := s.Treasures[name]
+		if t.id != id || (t.addr.offset == 0) != fake {
+			continue
+		}
+		if len(slot.subIDAddrs) == 0 || slot.subIDAddrs[0].offset == 0 ||
+			b[slot.subIDAddrs[0].fullOffset()] == t.subID {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// returns true iff every address of the range contains the given data.
+func rangeMatches(b []byte, mut *MutableRange, data []byte) bool {
+	for _, addr := range mut.Addrs {
+		offset := addr.fullOffset()
+		if offset+len(data) > len(b) ||
+			!bytes.Equal(b[offset:offset+len(data)], data) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the report as human-readable text.
+func (r *Report) String() string {
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintf(buf, "sha-1 sum: %x\n", r.Sum)
+
+	fmt.Fprintf(buf, "\n-- slots --\n\n")
+	slotNames := make([]string, 0, len(r.Slots))
+	for name := range r.Slots {
+		slotNames = append(slotNames, name)
+	}
+	sort.Strings(slotNames)
+	for _, name := range slotNames {
+		treasure := r.Slots[name]
+		if treasure == "" {
+			treasure = "(unknown)"
+		}
+		fmt.Fprintf(buf, "%-28s <- %s\n", name, treasure)
+	}
+
+	fmt.Fprintf(buf, "\n-- unapplied code patches --\n\n")
+	for _, name := range r.Unapplied {
+		fmt.Fprintln(buf, name)
+	}
+
+	fmt.Fprintf(buf, "\n-- mismatched code patches --\n\n")
+	for _, name := range r.Mismatched {
+		fmt.Fprintln(buf, name)
+	}
+
+	return buf.String()
+}
@@ -18,35 +18,90 @@ const (
 	GameSeasons
 )
 
-var itemGfx map[string]int
+// A State holds the data for one game that the randomizer reads and changes,
+// from the contents of item slots to the code appended to each bank. States
+// don't share any data that they change, so more than one can be used at once.
+type State struct {
+	Game      int
+	ItemSlots map[string]*MutableSlot
+	Treasures map[string]*Treasure
+	Seasons   map[string]*MutableRange // default seasons; nil for ages
+
+	// these mutables have fixed addresses and don't reference other
+	// mutables. try to generally order them by address, unless a grouping
+	// between mutables in different banks makes more sense.
+	fixedMutables map[string]Mutable
+
+	// like the item slots, these are (usually) no-ops until the randomizer
+	// touches them. these are also fixed, but generally need to have their
+	// values set elsewhere in order to do anything.
+	varMutables map[string]Mutable
+
+	// code appended to the ends of banks, and changes to existing code.
+	codeMutables map[string]Mutable
+
+	// if non-nil, the compass also beeps in dungeon rooms with slots holding
+	// treasures that this returns true for.
+	compassHint func(treasureName string) bool
+
+	// slots marked on the treasure map instead of the jewels, if any.
+	treasureMapSlots []string
+
+	startingHearts int
+
+	// cartridge header changes; see SetTitle and SetLicensee.
+	title    string
+	licensee int
+}
+
+// NewState returns a State for the given game, with every slot holding its
+// vanilla treasure.
+func NewState(game int) *State {
+	s := &State{
+		Game:           game,
+		codeMutables:   make(map[string]Mutable),
+		startingHearts: VanillaHearts,
+		licensee:       -1,
+	}
 
-func Init(game int) {
 	if game == GameAges {
-		ItemSlots = agesSlots
-		Treasures = agesTreasures
-		fixedMutables = agesFixedMutables
-		varMutables = agesVarMutables
-		itemGfx = agesItemGfx
-		initAgesEOB()
+		s.ItemSlots = copySlots(agesSlots)
+		s.Treasures = copyTreasures(agesTreasures, agesItemGfx)
+		s.fixedMutables = copyMutables(agesFixedMutables)
+		s.varMutables = copyMutables(agesVarMutables)
+		s.initAgesEOB()
 	} else {
-		ItemSlots = seasonsSlots
-		Treasures = seasonsTreasures
-		fixedMutables = seasonsFixedMutables
-		varMutables = seasonsVarMutables
-		itemGfx = seasonsItemGfx
-		initSeasonsEOB()
-
-		for k, v := range Seasons {
-			varMutables[k] = v
+		s.ItemSlots = copySlots(seasonsSlots)
+		s.Treasures = copyTreasures(seasonsTreasures, seasonsItemGfx)
+		s.fixedMutables = copyMutables(seasonsFixedMutables)
+		s.varMutables = copyMutables(seasonsVarMutables)
+		s.initSeasonsEOB()
+
+		s.Seasons = make(map[string]*MutableRange, len(defaultSeasons))
+		for k, v := range defaultSeasons {
+			s.Seasons[k] = v.copy()
+			s.varMutables[k] = s.Seasons[k]
 		}
 	}
 
-	for _, slot := range ItemSlots {
-		slot.Treasure = Treasures[slot.treasureName]
+	for _, slot := range s.ItemSlots {
+		slot.Treasure = s.Treasures[slot.treasureName]
 	}
 
+	return s
+}
+
+// the package's tables for each game are never changed after this, since each
+// State works on copies of them.
+func init() {
+	fillItemGfx(agesItemGfx, agesTreasures)
+	fillItemGfx(seasonsItemGfx, seasonsTreasures)
+}
+
+// adds graphics for treasures that share another treasure's sprite.
+func fillItemGfx(itemGfx map[string]int, treasures map[string]*Treasure) {
 	// rings and boss keys all have the same sprite
-	for name, treasure := range Treasures {
+	for name, treasure := range treasures {
 		if treasure.id == 0x2d {
 			itemGfx[name] = itemGfx["ring"]
 		}
@@ -76,17 +131,6 @@ func Init(game int) {
 	itemGfx["harp 3"] = itemGfx["tune of ages"]
 	itemGfx["flippers 1"] = itemGfx["flippers"]
 	itemGfx["flippers 2"] = itemGfx["mermaid suit"]
-
-	// get set of unique items (to determine which can be slotted freely)
-	treasureCounts := make(map[string]int)
-	for _, slot := range ItemSlots {
-		name := FindTreasureName(slot.Treasure)
-		if treasureCounts[name] == 0 {
-			treasureCounts[name] = 1
-		} else {
-			treasureCounts[name]++
-		}
-	}
 }
 
 // Addr is a fully-specified memory address.
@@ -105,12 +149,15 @@ func (a *Addr) fullOffset() int {
 	return bankOffset + int(a.offset)
 }
 
+// the manufacturer codes are checked too, since the title can be changed.
 func IsAges(b []byte) bool {
-	return string(b[0x134:0x13f]) == "ZELDA NAYRU"
+	return string(b[0x134:0x13f]) == "ZELDA NAYRU" ||
+		string(b[0x13f:0x142]) == "AZ8"
 }
 
 func IsSeasons(b []byte) bool {
-	return string(b[0x134:0x13d]) == "ZELDA DIN"
+	return string(b[0x134:0x13d]) == "ZELDA DIN" ||
+		string(b[0x13f:0x142]) == "AZ7"
 }
 
 func IsUS(b []byte) bool {
@@ -139,72 +186,111 @@ func orderedKeys(m map[string]Mutable) []string {
 	return keys
 }
 
-// Mutate changes the contents of loaded ROM bytes in place. It returns a
-// checksum of the result or an error.
-func Mutate(b []byte, game int) ([]byte, error) {
-	if game == GameSeasons {
-		varMutables["initial season"].(*MutableRange).New =
-			[]byte{0x2d, Seasons["north horon season"].New[0]}
-		codeMutables["season after pirate cutscene"].(*MutableRange).New =
-			[]byte{Seasons["western coast season"].New[0]}
-
-		setTreasureMapData()
-
-		// explicitly set these addresses and IDs after their functions
-		codeAddr := codeMutables["star ore id func"].(*MutableRange).Addrs[0]
-		ItemSlots["subrosia seaside"].idAddrs[0].offset = codeAddr.offset + 2
-		ItemSlots["subrosia seaside"].subIDAddrs[0].offset = codeAddr.offset + 5
-		codeAddr = codeMutables["hard ore id func"].(*MutableRange).Addrs[0]
-		ItemSlots["great furnace"].idAddrs[0].offset = codeAddr.offset + 2
-		ItemSlots["great furnace"].subIDAddrs[0].offset = codeAddr.offset + 5
-		codeAddr = codeMutables["diver fake id script"].(*MutableRange).Addrs[0]
-		ItemSlots["master diver's reward"].idAddrs[0].offset = codeAddr.offset + 1
-		ItemSlots["master diver's reward"].subIDAddrs[0].offset = codeAddr.offset + 2
-	} else {
-		// explicitly set these addresses and IDs after their functions
-		mut := codeMutables["soldier script give item"].(*MutableRange)
-		slot := ItemSlots["deku forest soldier"]
-		slot.idAddrs[0].offset = mut.Addrs[0].offset + 13
-		slot.subIDAddrs[0].offset = mut.Addrs[0].offset + 14
-		codeAddr := codeMutables["target carts flag"].(*MutableRange).Addrs[0]
-		ItemSlots["target carts 2"].idAddrs[1].offset = codeAddr.offset + 1
-		ItemSlots["target carts 2"].subIDAddrs[1].offset = codeAddr.offset + 2
-	}
+// these slots write their IDs into code mutables, so they have to be applied
+// after the code is written.
+var lateSlotNames = map[int][]string{
+	GameSeasons: {"subrosia seaside", "great furnace", "master diver's reward"},
+	GameAges: {"nayru's house", "deku forest soldier", "target carts 2",
+		"hidden tokay cave"},
+}
 
-	setSeedData(game)
+type namedMutable struct {
+	name string
+	mut  Mutable
+}
 
-	var err error
-	mutables := getAllMutables()
-	for _, k := range orderedKeys(mutables) {
-		err = mutables[k].Mutate(b)
-		if err != nil {
-			return nil, err
+// returns all mutables in the order they're applied: sorted by name so that
+// sums are consistent with the same seed, except for the late slots.
+func (s *State) orderedMutables() []namedMutable {
+	all := s.getAllMutables()
+	late := lateSlotNames[s.Game]
+	named := make([]namedMutable, 0, len(all))
+	for name, mut := range all {
+		named = append(named, namedMutable{name, mut})
+	}
+	sort.Slice(named, func(i, j int) bool {
+		return named[i].name < named[j].name
+	})
+
+	isLate := make(map[string]bool, len(late))
+	for _, name := range late {
+		isLate[name] = true
+	}
+	muts := make([]namedMutable, 0, len(named))
+	for _, nm := range named {
+		if !isLate[nm.name] {
+			muts = append(muts, nm)
 		}
 	}
+	for _, name := range late {
+		muts = append(muts, namedMutable{name, s.ItemSlots[name]})
+	}
 
-	// explicitly set these IDs after their functions are written
-	if game == GameSeasons {
-		ItemSlots["subrosia seaside"].Mutate(b)
-		ItemSlots["great furnace"].Mutate(b)
-		ItemSlots["master diver's reward"].Mutate(b)
-	} else {
-		ItemSlots["nayru's house"].Mutate(b)
-		ItemSlots["deku forest soldier"].Mutate(b)
-		ItemSlots["target carts 2"].Mutate(b)
-		ItemSlots["hidden tokay cave"].Mutate(b)
+	return muts
+}
+
+// Mutate changes the contents of loaded ROM bytes in place, and fixes the
+// header checksums to match. It returns a checksum of the result or an error.
+func (s *State) Mutate(b []byte) ([]byte, error) {
+	s.prepareMutables()
+
+	for _, nm := range s.orderedMutables() {
+		if err := nm.mut.Mutate(b); err != nil {
+			return nil, err
+		}
 	}
 
-	setCompassData(b, game)
+	s.setCompassData(b)
+	s.setHeader(b)
 
 	outSum := sha1.Sum(b)
 	return outSum[:], nil
 }
 
-// Verify checks all the package's data against the ROM to see if it matches.
+// sets the values of mutables that depend on other mutables.
+func (s *State) prepareMutables() {
+	if s.Game == GameSeasons {
+		s.varMutables["initial season"].(*MutableRange).New =
+			[]byte{0x2d, s.Seasons["north horon season"].New[0]}
+		s.codeMutables["season after pirate cutscene"].(*MutableRange).New =
+			[]byte{s.Seasons["western coast season"].New[0]}
+
+		s.setTreasureMapData()
+	}
+
+	s.setDynamicSlotAddrs()
+	s.setSeedData()
+}
+
+// explicitly set the addresses of slots whose IDs are part of appended
+// functions.
+func (s *State) setDynamicSlotAddrs() {
+	if s.Game == GameSeasons {
+		codeAddr := s.codeMutables["star ore id func"].(*MutableRange).Addrs[0]
+		s.ItemSlots["subrosia seaside"].idAddrs[0].offset = codeAddr.offset + 2
+		s.ItemSlots["subrosia seaside"].subIDAddrs[0].offset = codeAddr.offset + 5
+		codeAddr = s.codeMutables["hard ore id func"].(*MutableRange).Addrs[0]
+		s.ItemSlots["great furnace"].idAddrs[0].offset = codeAddr.offset + 2
+		s.ItemSlots["great furnace"].subIDAddrs[0].offset = codeAddr.offset + 5
+		codeAddr = s.codeMutables["diver fake id script"].(*MutableRange).Addrs[0]
+		s.ItemSlots["master diver's reward"].idAddrs[0].offset = codeAddr.offset + 1
+		s.ItemSlots["master diver's reward"].subIDAddrs[0].offset = codeAddr.offset + 2
+	} else {
+		mut := s.codeMutables["soldier script give item"].(*MutableRange)
+		slot := s.ItemSlots["deku forest soldier"]
+		slot.idAddrs[0].offset = mut.Addrs[0].offset + 13
+		slot.subIDAddrs[0].offset = mut.Addrs[0].offset + 14
+		codeAddr := s.codeMutables["target carts flag"].(*MutableRange).Addrs[0]
+		s.ItemSlots["target carts 2"].idAddrs[1].offset = codeAddr.offset + 1
+		s.ItemSlots["target carts 2"].subIDAddrs[1].offset = codeAddr.offset + 2
+	}
+}
+
+// Verify checks all the state's data against the ROM to see if it matches.
 // It returns a slice of errors describing each mismatch.
-func Verify(b []byte, game int) []error {
+func (s *State) Verify(b []byte) []error {
 	errors := make([]error, 0)
-	for k, m := range getAllMutables() {
+	for k, m := range s.getAllMutables() {
 		// ignore special cases that would error even when correct
 		switch k {
 		// flutes
@@ -256,28 +342,28 @@ func Verify(b []byte, game int) []error {
 // set the initial satchel and slingshot seeds (and selections) based on what
 // grows on the horon village tree, and set the map icon for each tree to match
 // the seed type.
-func setSeedData(game int) {
+func (s *State) setSeedData() {
 	var seedType byte
-	if game == GameSeasons {
-		seedType = ItemSlots["horon village seed tree"].Treasure.id
+	if s.Game == GameSeasons {
+		seedType = s.ItemSlots["horon village seed tree"].Treasure.id
 	} else {
-		seedType = ItemSlots["south lynna tree"].Treasure.id
+		seedType = s.ItemSlots["south lynna tree"].Treasure.id
 	}
 
-	if game == GameSeasons {
+	if s.Game == GameSeasons {
 		for _, name := range []string{"satchel initial seeds",
 			"carry seeds in slingshot"} {
-			mut := varMutables[name].(*MutableRange)
+			mut := s.varMutables[name].(*MutableRange)
 			mut.New[0] = 0x20 + seedType
 		}
 
 		// slingshot starting seeds
-		varMutables["edit gain/lose items tables"].(*MutableRange).New[1] =
+		s.varMutables["edit gain/lose items tables"].(*MutableRange).New[1] =
 			0x20 + seedType
 
 		for _, name := range []string{
 			"satchel initial selection", "slingshot initial selection"} {
-			mut := varMutables[name].(*MutableRange)
+			mut := s.varMutables[name].(*MutableRange)
 			mut.New[1] = seedType
 		}
 
@@ -289,42 +375,42 @@ func setSeedData(game int) {
 			"sunken city seed tree map icon",
 			"tarm ruins seed tree map icon",
 		} {
-			mut := varMutables[name].(*MutableRange)
+			mut := s.varMutables[name].(*MutableRange)
 			slotName := strings.Replace(name, " map icon", "", 1)
-			id := ItemSlots[slotName].Treasure.id
+			id := s.ItemSlots[slotName].Treasure.id
 			mut.New[0] = 0x15 + id
 		}
 	} else {
 		// set high nybbles (seed types) of seed tree interactions
-		setTreeNybble(varMutables["symmetry city tree sub ID"],
-			ItemSlots["symmetry city tree"])
-		setTreeNybble(varMutables["south lynna present tree sub ID"],
-			ItemSlots["south lynna tree"])
-		setTreeNybble(varMutables["crescent island tree sub ID"],
-			ItemSlots["crescent island tree"])
-		setTreeNybble(varMutables["zora village present tree sub ID"],
-			ItemSlots["zora village tree"])
-		setTreeNybble(varMutables["rolling ridge west tree sub ID"],
-			ItemSlots["rolling ridge west tree"])
-		setTreeNybble(varMutables["ambi's palace tree sub ID"],
-			ItemSlots["ambi's palace tree"])
-		setTreeNybble(varMutables["rolling ridge east tree sub ID"],
-			ItemSlots["rolling ridge east tree"])
-		setTreeNybble(varMutables["south lynna past tree sub ID"],
-			ItemSlots["south lynna tree"])
-		setTreeNybble(varMutables["deku forest tree sub ID"],
-			ItemSlots["deku forest tree"])
-		setTreeNybble(varMutables["zora village past tree sub ID"],
-			ItemSlots["zora village tree"])
+		setTreeNybble(s.varMutables["symmetry city tree sub ID"],
+			s.ItemSlots["symmetry city tree"])
+		setTreeNybble(s.varMutables["south lynna present tree sub ID"],
+			s.ItemSlots["south lynna tree"])
+		setTreeNybble(s.varMutables["crescent island tree sub ID"],
+			s.ItemSlots["crescent island tree"])
+		setTreeNybble(s.varMutables["zora village present tree sub ID"],
+			s.ItemSlots["zora village tree"])
+		setTreeNybble(s.varMutables["rolling ridge west tree sub ID"],
+			s.ItemSlots["rolling ridge west tree"])
+		setTreeNybble(s.varMutables["ambi's palace tree sub ID"],
+			s.ItemSlots["ambi's palace tree"])
+		setTreeNybble(s.varMutables["rolling ridge east tree sub ID"],
+			s.ItemSlots["rolling ridge east tree"])
+		setTreeNybble(s.varMutables["south lynna past tree sub ID"],
+			s.ItemSlots["south lynna tree"])
+		setTreeNybble(s.varMutables["deku forest tree sub ID"],
+			s.ItemSlots["deku forest tree"])
+		setTreeNybble(s.varMutables["zora village past tree sub ID"],
+			s.ItemSlots["zora village tree"])
 
 		// satchel and shooter come with south lynna tree seeds
-		mut := varMutables["satchel initial seeds"].(*MutableRange)
+		mut := s.varMutables["satchel initial seeds"].(*MutableRange)
 		mut.New[0] = 0x20 + seedType
-		mut = codeMutables["fill seed shooter"].(*MutableRange)
+		mut = s.codeMutables["fill seed shooter"].(*MutableRange)
 		mut.New[6] = 0x20 + seedType
 		for _, name := range []string{"satchel initial selection",
 			"shooter initial selection"} {
-			mut := varMutables[name].(*MutableRange)
+			mut := s.varMutables[name].(*MutableRange)
 			mut.New[1] = seedType
 		}
 
@@ -333,8 +419,8 @@ func setSeedData(game int) {
 			"symmetry city tree", "south lynna tree", "zora village tree",
 			"rolling ridge west tree", "ambi's palace tree",
 			"rolling ridge east tree", "deku forest tree"} {
-			mut := varMutables[name+" map icon"].(*MutableRange)
-			mut.New[0] = 0x15 + ItemSlots[name].Treasure.id
+			mut := s.varMutables[name+" map icon"].(*MutableRange)
+			mut.New[0] = 0x15 + s.ItemSlots[name].Treasure.id
 		}
 	}
 }
@@ -345,17 +431,23 @@ func setTreeNybble(subID Mutable, slot *MutableSlot) {
 	mut.New[0] = (mut.Old[0] & 0x0f) | (slot.Treasure.id << 4)
 }
 
-// set the locations of the sparkles for the jewels on the treasure map.
-func setTreasureMapData() {
-	for _, name := range []string{"round", "pyramid", "square", "x-shaped"} {
-		mut := varMutables[name+" jewel coords"].(*MutableRange)
-		slot := lookupItemSlot(name + " jewel")
+// set the locations of the sparkles for the jewels on the treasure map, or
+// for the slots given by SetTreasureMapSlots.
+func (s *State) setTreasureMapData() {
+	for i, name := range TreasureMapJewels {
+		mut := s.varMutables[name+" jewel coords"].(*MutableRange)
+		slot := s.lookupItemSlot(name + " jewel")
+		if i < len(s.treasureMapSlots) {
+			slot = s.ItemSlots[s.treasureMapSlots[i]]
+		}
 		mut.New[0] = slot.mapCoords
 	}
 }
 
-// match the compass's beep beep beep boops to the actual boss key locations.
-func setCompassData(b []byte, game int) {
+// match the compass's beep beep beep boops to the actual boss key locations,
+// and to the locations of other items picked by the compass hint, if any.
+func (s *State) setCompassData(b []byte) {
+	game := s.Game
 	var names []string
 	if game == GameSeasons {
 		names = []string{"d1 goriya chest", "d2 terrace chest",
@@ -369,7 +461,7 @@ func setCompassData(b []byte, game int) {
 
 	// clear original boss key flags
 	for _, name := range names {
-		slot := ItemSlots[name]
+		slot := s.ItemSlots[name]
 		offset :=
 			getDungeonPropertiesAddr(game, slot.group, slot.room).fullOffset()
 		b[offset] = b[offset] & 0xef // reset bit 4
@@ -378,18 +470,33 @@ func setCompassData(b []byte, game int) {
 	// add new boss key flags
 	for i := 1; i <= 8; i++ {
 		name := fmt.Sprintf("d%d boss key", i)
-		slot := lookupItemSlot(name)
+		slot := s.lookupItemSlot(name)
 		offset :=
 			getDungeonPropertiesAddr(game, slot.group, slot.room).fullOffset()
 		b[offset] = (b[offset] & 0xbf) | 0x10 // set bit 4, reset bit 6
 	}
+
+	if s.compassHint == nil {
+		return
+	}
+	for _, slot := range s.ItemSlots {
+		// only groups 4 and 5 have dungeon room properties.
+		if slot.group != 4 && slot.group != 5 {
+			continue
+		}
+		if s.compassHint(s.FindTreasureName(slot.Treasure)) {
+			offset := getDungeonPropertiesAddr(
+				game, slot.group, slot.room).fullOffset()
+			b[offset] = (b[offset] & 0xbf) | 0x10 // same as boss keys
+		}
+	}
 }
 
 // returns the slot where the named item was placed. this only works for unique
 // items, of course.
-func lookupItemSlot(itemName string) *MutableSlot {
-	t := Treasures[itemName]
-	for _, slot := range ItemSlots {
+func (s *State) lookupItemSlot(itemName string) *MutableSlot {
+	t := s.Treasures[itemName]
+	for _, slot := range s.ItemSlots {
 		if slot.Treasure == t {
 			return slot
 		}
@@ -1,14 +1,14 @@
 package rom
 
-import "testing"
-
-func init() {
-	Init(GameAges) // XXX have to change this manually to test each game
-}
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
 
 func TestGraphicsPresent(t *testing.T) {
-	for name, _ := range Treasures {
-		if itemGfx[name] == 0 {
+	for name, _ := range agesTreasures {
+		if agesItemGfx[name] == 0 {
 			t.Errorf("no graphics for %s", name)
 		}
 	}
@@ -17,7 +17,7 @@ func TestGraphicsPresent(t *testing.T) {
 func TestMutableOverlap(t *testing.T) {
 	hitBytes := make(map[int]*string)
 
-	for k, v := range getAllMutables() {
+	for k, v := range NewState(GameAges).getAllMutables() {
 		k := k
 		switch v := v.(type) {
 		case *MutableRange:
@@ -34,3 +34,232 @@ func TestMutableOverlap(t *testing.T) {
 		}
 	}
 }
+
+func TestStatesIndependent(t *testing.T) {
+	a, b := NewState(GameSeasons), NewState(GameSeasons)
+	a.ItemSlots["eyeglass lake, across bridge"].Treasure =
+		a.Treasures["flippers"]
+	a.Seasons["north horon season"].New[0] = 0x00
+	a.SetTreewarp(false)
+	if _, err := a.Mutate(make([]byte, 0x100000)); err != nil {
+		t.Fatal(err)
+	}
+
+	slot := b.ItemSlots["eyeglass lake, across bridge"]
+	if name := b.FindTreasureName(slot.Treasure); name != "gasha seed" {
+		t.Errorf("slot changed in other state: %s", name)
+	}
+	if b.Seasons["north horon season"].New[0] != 0x03 {
+		t.Error("season changed in other state")
+	}
+	mut := b.codeMutables["tree warp jump"].(*MutableRange)
+	if bytes.Equal(mut.New, mut.Old) {
+		t.Error("code changed in other state")
+	}
+}
+
+func TestBankBudget(t *testing.T) {
+	r := &romBanks{
+		endOfBank: make([]uint16, 0x40),
+		mutables:  make(map[string]Mutable),
+	}
+	r.endOfBank[0x00] = 0x3ffe
+
+	// should fit exactly
+	if addr := r.appendToBank(0x00, "test 1", "\x00\x00"); addr != "\xfe\x3f" {
+		t.Errorf("want addr 3ffe, got %x", addr)
+	}
+
+	// overflow should be reported only when checked
+	r.appendToBank(0x00, "test 3", "\x00")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for over-budget bank")
+		}
+	}()
+	r.checkBudget()
+}
+
+func TestDiff(t *testing.T) {
+	s := NewState(GameAges)
+	b := make([]byte, 0x100000)
+	changes, err := s.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, make([]byte, len(b))) {
+		t.Fatal("Diff modified ROM data")
+	}
+
+	// applying the changes should give the same result as mutating.
+	for _, c := range changes {
+		if b[c.Offset] != c.Old {
+			t.Errorf("%v: found %02x", c, b[c.Offset])
+		}
+		b[c.Offset] = c.New
+	}
+	want := make([]byte, len(b))
+	if _, err := s.Mutate(want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Error("changes don't match Mutate")
+	}
+}
+
+func BenchmarkMutate(b *testing.B) {
+	rom := make([]byte, 0x100000)
+	s := NewState(GameAges)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Mutate(rom); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStartingHearts(t *testing.T) {
+	s := NewState(GameAges)
+	if err := s.SetStartingHearts(MaxHearts + 1); err == nil {
+		t.Errorf("expected error for %d hearts", MaxHearts+1)
+	}
+	if err := s.SetStartingHearts(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStartingItems([]string{"feather"}); err != nil {
+		t.Fatal(err)
+	}
+	table := s.codeMutables["starting items table"].(*MutableRange).New
+	if table[2] != heartContainerID || table[3] != 8 || table[4] != 0xff {
+		t.Errorf("bad starting items table: % x", table)
+	}
+
+	items := make([]string, maxStartingItems)
+	for i := range items {
+		items[i] = "feather"
+	}
+	if err := s.SetStartingItems(items); err == nil {
+		t.Errorf("expected error for too many items with extra hearts")
+	}
+}
+
+func TestCompassHint(t *testing.T) {
+	s := NewState(GameAges)
+	slot := s.ItemSlots["d7 miniboss chest"]
+	offset := getDungeonPropertiesAddr(
+		GameAges, slot.group, slot.room).fullOffset()
+
+	b := make([]byte, 0x100000)
+	s.setCompassData(b)
+	if b[offset]&0x10 != 0 {
+		t.Fatal("room beeps without a hint")
+	}
+
+	s.SetCompassHint(func(name string) bool { return name == "switch hook 2" })
+	s.setCompassData(b)
+	if b[offset]&0x10 == 0 {
+		t.Error("room doesn't beep with a hint")
+	}
+}
+
+func TestMemoryMap(t *testing.T) {
+	m := MakeMemoryMap(GameAges)
+
+	feather := m.Treasures["feather"]
+	if feather.Addr != 0xc69a+0x17/8 || feather.Mask != 1<<(0x17%8) {
+		t.Errorf("bad feather flag: %+v", feather)
+	}
+
+	chest := m.Slots["d7 miniboss chest"]
+	if chest == nil || chest.Addr != 0xca4e || chest.Mask != 0x20 {
+		t.Errorf("bad d7 miniboss chest flag: %+v", chest)
+	}
+	if m.Slots["deku forest tree"] != nil {
+		t.Error("seed tree has a room flag")
+	}
+
+	feather = MakeMemoryMap(GameSeasons).Treasures["feather 1"]
+	if feather.Addr != 0xc692+0x17/8 || feather.Mask != 1<<(0x17%8) {
+		t.Errorf("bad seasons feather flag: %+v", feather)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	b := make([]byte, 0x100000)
+	copy(b[0x134:], "ZELDA DIN\x00\x00AZ7E")
+	b[0x14b] = 0x33
+	b[0x4000] = 0xff
+
+	s := NewState(GameSeasons)
+	if err := s.SetTitle("oos rando"); err == nil {
+		t.Error("no error for lowercase title")
+	}
+	if err := s.SetTitle("OOS RANDO"); err != nil {
+		t.Fatal(err)
+	}
+	s.SetLicensee(0x01)
+	s.setHeader(b)
+
+	if string(b[0x134:0x13f]) != "OOS RANDO\x00\x00" {
+		t.Errorf("title is %q", b[0x134:0x13f])
+	}
+	if !IsSeasons(b) {
+		t.Error("retitled ROM isn't recognized")
+	}
+	if b[0x14b] != 0x01 {
+		t.Errorf("licensee is %02x", b[0x14b])
+	}
+
+	// the boot ROM's check: the header bytes and checksum sum to -0x19.
+	var x byte
+	for _, v := range b[0x134:0x14e] {
+		x += v
+	}
+	if x != 0xe7 {
+		t.Errorf("bad header checksum %02x", b[0x14d])
+	}
+	if sum := globalChecksum(b); b[0x14e] != byte(sum>>8) ||
+		b[0x14f] != byte(sum) {
+		t.Errorf("global checksum is %02x%02x, want %04x",
+			b[0x14e], b[0x14f], sum)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	s := NewState(GameAges)
+	s.SetInfo("seed: 1234abcd\n" + strings.Repeat("x", infoSize))
+
+	b := make([]byte, 0x100000)
+	if _, ok := ReadInfo(b); ok {
+		t.Error("found info in blank ROM")
+	}
+	if _, err := s.Mutate(b); err != nil {
+		t.Fatal(err)
+	}
+	if info, ok := ReadInfo(b); !ok || info != "seed: 1234abcd" {
+		t.Errorf("got info %q, %v", info, ok)
+	}
+}
+
+func TestSkips(t *testing.T) {
+	for _, game := range []int{GameSeasons, GameAges} {
+		s := NewState(game)
+		for name, muts := range gameSkips(game) {
+			for _, mut := range muts {
+				if s.fixedMutables[mut] == nil {
+					t.Errorf("skip %s: no mutable %s", name, mut)
+				}
+			}
+			if err := s.KeepCutscene(name); err != nil {
+				t.Error(err)
+			}
+			for _, mut := range muts {
+				if s.fixedMutables[mut] != nil {
+					t.Errorf("skip %s: %s still applied", name, mut)
+				}
+			}
+		}
+		if err := s.KeepCutscene("onox intro"); err == nil {
+			t.Error("no error for unknown cutscene")
+		}
+	}
+}
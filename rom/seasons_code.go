@@ -35,8 +35,9 @@ var (
 	starOreRooms  = []byte{0x66, 0x76, 0x75, 0x65}
 )
 
-func initSeasonsEOB() {
+func (s *State) initSeasonsEOB() {
 	r := newSeasonsRomBanks()
+	r.mutables = s.codeMutables
 
 	// try to order these first by bank, then by call location. maybe group
 	// them into subfunctions when applicable?
@@ -44,16 +45,35 @@ func initSeasonsEOB() {
 	// bank 00
 
 	// don't play any music if the -nomusic flag is given.
-	noMusicFunc := r.appendToBank(0x00, "no music func",
-		"\x67\xfe\x47\x30\x03\x3e\x08\xc9\xf0\xb5\xc9")
+	noMusicFunc := r.appendASM(0x00, "no music func", `
+		ld h,a
+		cp $47
+		jr nc,.sound
+		ld a,$08
+		ret
+	.sound:
+		ldh a,($b5)
+		ret`)
 	r.replace(0x00, 0x0c76, "no music call",
 		"\x67\xf0\xb5", "\xcd"+noMusicFunc)
 
 	// force the item in the temple of seasons cutscene to use normal item
 	// animations.
-	rodCutsceneGfxFunc := r.appendToBank(0x00, "rod cutscene gfx func",
-		"\x1e\x41\x1a\xfe\xe6\xc0\x1c\x1a\xfe\x02\x28\x03\x1d\x1a\xc9"+
-			"\x3e\x60\xc9")
+	rodCutsceneGfxFunc := r.appendASM(0x00, "rod cutscene gfx func", `
+		ld e,$41
+		ld a,(de)
+		cp $e6
+		ret nz
+		inc e
+		ld a,(de)
+		cp $02
+		jr z,.rod
+		dec e
+		ld a,(de)
+		ret
+	.rod:
+		ld a,$60
+		ret`)
 	r.replace(0x00, 0x2600, "rod cutscene gfx call",
 		"\x1e\x41\x1a", "\xcd"+rodCutsceneGfxFunc)
 
@@ -408,6 +428,7 @@ func initSeasonsEOB() {
 	// well as some other flags to skip cutscenes, etc.
 	initialGlobalFlags := r.appendToBank(0x0a, "initial global flags",
 		"\x0a\x1c\xff")
+	giveStartingItems := appendStartingItems(r, 0x0a, 0x16eb)
 	setStartingFlags := r.appendToBank(0x0a, "set starting flags",
 		"\xe5\x21"+initialGlobalFlags+"\x2a\xfe\xff\x28\x07"+
 			"\xe5\xcd\xcd\x30\xe1\x18\xf4\xe1"+ // init global flags
@@ -418,7 +439,7 @@ func initSeasonsEOB() {
 			"\x3e\x40\xea\xb6\xc7\xea\x2a\xc8\xea\x00\xc8"+ // bit 6
 			"\xea\x00\xc7\xea\x96\xc7\xea\x8d\xc7\xea\x60\xc7\xea\xd0\xc7"+
 			"\xea\x1d\xc7\xea\x8a\xc7\xea\xe9\xc7\xea\x9b\xc7\xea\x29\xc8"+
-			"\xc9")
+			"\xc3"+giveStartingItems)
 	r.replace(0x0a, 0x66ed, "call set starting flags",
 		"\x1e\x78\x1a", "\xc3"+setStartingFlags)
 
@@ -482,7 +503,7 @@ func initSeasonsEOB() {
 	// entry, (group, room, collect mode). ff ends the table. rooms that
 	// contain more than one item are special cases.
 	collectModeTable := r.appendToBank(0x15, "collection mode table",
-		makeSeasonsCollectModeTable())
+		s.makeSeasonsCollectModeTable())
 	// cp link's position if in diver room, set mode to 02 if on right side,
 	// ret z if set
 	collectModeDiver := r.appendToBank(0x15, "diver collect mode",
@@ -579,12 +600,16 @@ func initSeasonsEOB() {
 			"\x26\xc6\x6f\xfe\x45\x20\x04\xcb\xee\x18\x02\xcb\xfe"+
 			"\xe1\xd1\xf1\xcd\x4e\x45\xc9")
 	r.replace(0x3f, 0x452c, "flute set icon call", "\x4e\x45", setFluteIcon)
+
+	appendInfo(r)
+
+	r.checkBudget()
 }
 
 // makes seasons-specific additions to the collection mode table.
-func makeSeasonsCollectModeTable() string {
+func (s *State) makeSeasonsCollectModeTable() string {
 	b := new(strings.Builder)
-	table := makeCollectModeTable()
+	table := s.makeCollectModeTable()
 	b.WriteString(table[:len(table)-1]) // strip final ff
 
 	// add other three star ore screens
@@ -83,5 +83,5 @@ var seasonsItemGfx = map[string]int{
 // rod of seasons has a different graphics whatever than the rest of the slots
 // and it's tricky to change, so i'm restricting items instead.
 func CanSlotAsRod(name string) bool {
-	return (itemGfx[name] & 0xf) == 0
+	return (seasonsItemGfx[name] & 0xf) == 0
 }
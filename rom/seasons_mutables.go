@@ -438,7 +438,7 @@ var seasonsVarMutables = map[string]Mutable{
 	"file tunic color 21": MutableByte(Addr{0x02, 0x4ddc}, 0x20, 0x20),
 }
 
-var Seasons = map[string]*MutableRange{
+var defaultSeasons = map[string]*MutableRange{
 	// randomize default seasons (before routing). sunken city also applies to
 	// mt. cucco; eastern suburbs applies to the vertical part of moblin road
 	// but not the horizontal part. note that "tarm ruins" here refers only to
@@ -0,0 +1,56 @@
+package rom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// cutscene skips are named groups of fixed mutables that shorten cutscenes or
+// dialogue without changing what the player gets. all of them are applied by
+// default; KeepCutscene leaves a group's vanilla bytes instead.
+var seasonsSkips = map[string][]string{
+	"barrier":       {"abbreviate barrier cutscene"},
+	"furnace dance": {"skip furnace dance"},
+	"maku seed":     {"abbreviate maku seed cutscene"},
+}
+
+var agesSkips = map[string][]string{
+	"black tower": {"skip first black tower cutscene"},
+	"brothers":    {"skip brother text"},
+	"maku tree": {"abbreviate maku tree text",
+		"remove maku tree post-item text"},
+	"twinrova": {"skip twinrova cutscene"},
+}
+
+func gameSkips(game int) map[string][]string {
+	if game == GameSeasons {
+		return seasonsSkips
+	}
+	return agesSkips
+}
+
+// SkipNames returns the names of the game's cutscene skips in order.
+func SkipNames(game int) []string {
+	skips := gameSkips(game)
+	names := make([]string, 0, len(skips))
+	for name := range skips {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeepCutscene turns off the named cutscene skip, so that the cutscene plays
+// as in the vanilla game.
+func (s *State) KeepCutscene(name string) error {
+	muts, ok := gameSkips(s.Game)[name]
+	if !ok {
+		return fmt.Errorf("unknown cutscene %q; cutscenes are: %s", name,
+			strings.Join(SkipNames(s.Game), ", "))
+	}
+	for _, mut := range muts {
+		delete(s.fixedMutables, mut)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package rom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// this file contains an encoder and decoder for the games' message format, so
+// that text can be written as readable strings instead of byte strings.
+// messages are mostly ASCII. 00 ends a message, and 01 starts a new line,
+// which is written as "\n". the other codes below 10 are written in braces as
+// hex bytes, along with their parameter byte if they have one, e.g. "{07 03}"
+// to continue with message 03 of the same group. this includes 02 to 05,
+// which reference dictionaries of common substrings that aren't read from the
+// ROM. braces themselves and bytes from 7f up, which aren't ASCII, are written
+// the same way, e.g. "{7b}" for "{".
+
+const (
+	textEnd     = 0x00
+	textNewline = 0x01
+	textDict    = 0x02 // through 0x05
+	textJump    = 0x07
+	textMaxCode = 0x0f
+)
+
+// control codes known to be followed by a parameter byte.
+var textParamCodes = map[byte]bool{
+	0x06: true, // symbol
+	0x07: true, // jump to message
+	0x09: true, // color
+	0x0a: true, // player or child name
+	0x0c: true, // box position and speed
+	0x0e: true, // sound
+}
+
+// DecodeText returns the message at the start of b in readable form, and the
+// number of bytes it takes up. the message ends at a 00 byte, which isn't
+// included in the text, or after a jump to another message.
+func DecodeText(b []byte) (string, int, error) {
+	var sb strings.Builder
+
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == textEnd:
+			return sb.String(), i + 1, nil
+		case c == textNewline:
+			sb.WriteByte('\n')
+		case c == '{' || c == '}' || c >= 0x7f:
+			fmt.Fprintf(&sb, "{%02x}", c)
+		case c > textMaxCode:
+			sb.WriteByte(c)
+		default:
+			hasParam := c <= textDict+3 || textParamCodes[c]
+			if !hasParam {
+				fmt.Fprintf(&sb, "{%02x}", c)
+				continue
+			}
+			if i+1 >= len(b) {
+				return "", 0, fmt.Errorf("text code %02x missing parameter", c)
+			}
+			param := b[i+1]
+			i++
+			fmt.Fprintf(&sb, "{%02x %02x}", c, param)
+			if c == textJump {
+				return sb.String(), i + 1, nil
+			}
+		}
+	}
+
+	return "", 0, fmt.Errorf("text has no end")
+}
+
+// EncodeText returns the bytes for a message in the form given by DecodeText.
+// a 00 byte is appended unless the message ends with a jump.
+func EncodeText(s string) (string, error) {
+	var b []byte
+	jumped := false
+
+	for len(s) > 0 {
+		jumped = false
+		if s[0] == '{' {
+			end := strings.IndexByte(s, '}')
+			if end == -1 {
+				return "", fmt.Errorf("unclosed brace in text")
+			}
+			codes, err := parseTextCodes(s[1:end])
+			if err != nil {
+				return "", err
+			}
+			b = append(b, codes...)
+			jumped = len(codes) == 2 && codes[0] == textJump
+			s = s[end+1:]
+			continue
+		}
+
+		switch c := s[0]; {
+		case c == '\n':
+			b = append(b, textNewline)
+		case c > textMaxCode && c < 0x7f && c != '}':
+			b = append(b, c)
+		default:
+			return "", fmt.Errorf("can't encode %q in text", c)
+		}
+		s = s[1:]
+	}
+
+	if !jumped {
+		b = append(b, textEnd)
+	}
+	return string(b), nil
+}
+
+// parseTextCodes parses space-separated hex bytes from inside braces.
+func parseTextCodes(s string) ([]byte, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty braces in text")
+	}
+
+	codes := make([]byte, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseUint(field, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid text code %q", field)
+		}
+		codes[i] = byte(v)
+	}
+	return codes, nil
+}
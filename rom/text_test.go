@@ -0,0 +1,63 @@
+package rom
+
+import (
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	// "end warning text" from seasons, which uses dictionary references and
+	// ends normally.
+	b := []byte("\x0c\x00\x43\x6f\x6e\x74\x69\x6e\x75\x65\x20\x61\x74\x01" +
+		"\x03\x0b\x6f\x77\x6e\x20\x72\x69\x73\x6b\x21\x00\xff")
+
+	s, n, err := DecodeText(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{0c 00}Continue at\n{03 0b}own risk!"; s != want {
+		t.Errorf("want %q, got %q", want, s)
+	}
+	if n != len(b)-1 {
+		t.Errorf("want length %d, got %d", len(b)-1, n)
+	}
+
+	if _, _, err := DecodeText([]byte("no end")); err == nil {
+		t.Errorf("expected error for unterminated text")
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	// text should round-trip, including messages that end in a jump instead
+	// of 00.
+	for _, want := range []string{
+		"\x0c\x00\x02\x3b\x73\x6b\x69\x70\x01" +
+			"\x6b\x65\x79\x73\x2c\x04\xaa\x03\x2c\x01" +
+			"\x03\x70\x6c\x79\x03\xa4\x07\x03",
+		"\x0c\x00\x43\x6f\x6e\x74\x69\x6e\x75\x65\x20\x61\x74\x01" +
+			"\x03\x0b\x6f\x77\x6e\x20\x72\x69\x73\x6b\x21\x00",
+		"{a}\x7f\x80\xff\x10\x00",
+	} {
+		s, _, err := DecodeText([]byte(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := EncodeText(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%q: want %q, got %q", s, want, got)
+		}
+	}
+
+	s, _, _ := DecodeText([]byte("{a}\xe0\x00"))
+	if want := "{7b}a{7d}{e0}"; s != want {
+		t.Errorf("want %q, got %q", want, s)
+	}
+
+	for _, s := range []string{"{07", "{}", "{zz}", "café"} {
+		if _, err := EncodeText(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
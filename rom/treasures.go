@@ -34,6 +34,8 @@ type Treasure struct {
 	param  byte // parameter value to use for giveTreasure
 	text   byte
 	sprite byte
+
+	gfx int // sprite used in slots with graphics data
 }
 
 // ID returns the item ID of the treasure.
@@ -59,10 +61,8 @@ func (t Treasure) Mutate(b []byte) error {
 		return nil
 	}
 
-	addr, data := t.addr.fullOffset(), t.Bytes()
-	for i := 0; i < 4; i++ {
-		b[addr+i] = data[i]
-	}
+	addr := t.addr.fullOffset()
+	b[addr], b[addr+1], b[addr+2], b[addr+3] = t.mode, t.param, t.text, t.sprite
 	return nil
 }
 
@@ -76,28 +76,25 @@ func (t Treasure) Check(b []byte) error {
 	return nil
 }
 
-// Treasures maps item names to associated treasure data.
-var Treasures map[string]*Treasure
+// returns copies of the treasures, with graphics from the given map.
+func copyTreasures(m map[string]*Treasure,
+	itemGfx map[string]int) map[string]*Treasure {
+	c := make(map[string]*Treasure, len(m))
+	for k, v := range m {
+		t := *v
+		t.gfx = itemGfx[k]
+		c[k] = &t
+	}
+	return c
+}
 
 // FindTreasureName does a reverse lookup of the treasure in the map to return
 // its name. It returns an empty string if not found.
-func FindTreasureName(t *Treasure) string {
-	for k, v := range Treasures {
+func (s *State) FindTreasureName(t *Treasure) string {
+	for k, v := range s.Treasures {
 		if v == t {
 			return k
 		}
 	}
 	return ""
 }
-
-// returns true iff a treasure can be lost permanently (i.e. outside of hide
-// and seek).
-func TreasureCanBeLost(name string) bool {
-	switch name {
-	case "wooden shield", "shield L-2", "star ore", "ribbon",
-		"spring banana", "ricky's gloves", "round jewel", "pyramid jewel",
-		"square jewel", "x-shapred jewel", "red ore", "blue ore", "hard ore":
-		return true
-	}
-	return false
-}